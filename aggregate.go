@@ -0,0 +1,281 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// aggregateFuncs are the available aggregate functions.
+// The value reports whether the function requires a key.
+var aggregateFuncs = map[string]bool{
+	"count": false,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+// Aggregation is an aggregate function applied to a column, like "sum(/amount)".
+// count() counts rows, and count(/key) counts non-null values.
+// sum and avg ignore nulls, and fail on values that are not numbers.
+type Aggregation struct {
+	Func string
+	Key  string
+}
+
+// ParseAggregations parses comma separated aggregations like
+// "count(),sum(/amount),avg(/latency_ms)".
+func ParseAggregations(spec string) ([]*Aggregation, error) {
+	aggs := []*Aggregation{}
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		open := strings.Index(s, "(")
+		if open < 0 || !strings.HasSuffix(s, ")") {
+			return nil, fmt.Errorf("Invalid aggregation %q", s)
+		}
+		agg := &Aggregation{
+			Func: strings.TrimSpace(s[:open]),
+			Key:  strings.TrimSpace(s[open+1 : len(s)-1]),
+		}
+		requiresKey, ok := aggregateFuncs[agg.Func]
+		if !ok {
+			return nil, fmt.Errorf("Unknown aggregate function %q", agg.Func)
+		}
+		if agg.Key == "" && requiresKey {
+			return nil, fmt.Errorf("%s requires a column: %q", agg.Func, s)
+		}
+		if _, err := jsonpointer.New(agg.Key); err != nil {
+			return nil, err
+		}
+		aggs = append(aggs, agg)
+	}
+	return aggs, nil
+}
+
+// Name returns the column name like "sum(/amount)", where the key is
+// formatted with the formatter.
+func (a *Aggregation) Name(formatter KeyFormatter) string {
+	if a.Key == "" {
+		return a.Func + "()"
+	}
+	pointer, err := jsonpointer.New(a.Key)
+	if err != nil {
+		return a.Func + "(" + a.Key + ")"
+	}
+	return a.Func + "(" + formatter.FormatKey(pointer) + ")"
+}
+
+// Aggregator groups rows by the values of GroupBy columns and aggregates
+// each group. Groups are ordered by their first appearance.
+type Aggregator struct {
+	GroupBy      []string
+	Aggregations []*Aggregation
+
+	groups map[string]*aggregateGroup
+	order  []*aggregateGroup
+}
+
+type aggregateGroup struct {
+	values []interface{}
+	states []*aggregateState
+}
+
+type aggregateState struct {
+	count    int64
+	intSum   int64
+	floatSum float64
+	isFloat  bool
+	extremum interface{}
+}
+
+// NewAggregator returns new Aggregator.
+func NewAggregator(groupBy []string, aggregations []*Aggregation) *Aggregator {
+	return &Aggregator{
+		GroupBy:      groupBy,
+		Aggregations: aggregations,
+		groups:       map[string]*aggregateGroup{},
+	}
+}
+
+// Add adds the row to its group.
+func (a *Aggregator) Add(row KeyValue) error {
+	var key strings.Builder
+	values := make([]interface{}, 0, len(a.GroupBy))
+	for _, k := range a.GroupBy {
+		v := row[k]
+		writeValueKey(&key, v)
+		values = append(values, v)
+	}
+
+	g, ok := a.groups[key.String()]
+	if !ok {
+		g = &aggregateGroup{values: values}
+		for range a.Aggregations {
+			g.states = append(g.states, &aggregateState{})
+		}
+		a.groups[key.String()] = g
+		a.order = append(a.order, g)
+	}
+
+	for i, agg := range a.Aggregations {
+		if err := g.states[i].add(agg, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Header returns the names of the group-by columns formatted with the
+// formatter, followed by the names of the aggregations.
+func (a *Aggregator) Header(formatter KeyFormatter) ([]string, error) {
+	header := make([]string, 0, len(a.GroupBy)+len(a.Aggregations))
+	for _, key := range a.GroupBy {
+		pointer, err := jsonpointer.New(key)
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, formatter.FormatKey(pointer))
+	}
+	for _, agg := range a.Aggregations {
+		header = append(header, agg.Name(formatter))
+	}
+	return header, nil
+}
+
+// Rows returns a row for each group, which consists of the group-by values
+// and the aggregated values.
+func (a *Aggregator) Rows() [][]interface{} {
+	rows := make([][]interface{}, 0, len(a.order))
+	for _, g := range a.order {
+		row := make([]interface{}, 0, len(g.values)+len(g.states))
+		row = append(row, g.values...)
+		for i, agg := range a.Aggregations {
+			row = append(row, g.states[i].result(agg))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Records returns Rows formatted with the formatter. Nulls are empty.
+func (a *Aggregator) Records(formatter ValueFormatter) [][]string {
+	rows := a.Rows()
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		record := make([]string, 0, len(row))
+		for _, v := range row {
+			if v == nil {
+				record = append(record, "")
+			} else {
+				record = append(record, formatValue(formatter, v))
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func (s *aggregateState) add(agg *Aggregation, row KeyValue) error {
+	if agg.Key == "" {
+		s.count++
+		return nil
+	}
+	v := row[agg.Key]
+	if v == nil {
+		return nil
+	}
+	s.count++
+
+	switch agg.Func {
+	case "sum", "avg":
+		return s.addNumber(agg, v)
+	case "min":
+		if s.extremum == nil || compareValues(v, s.extremum) < 0 {
+			s.extremum = v
+		}
+	case "max":
+		if s.extremum == nil || compareValues(v, s.extremum) > 0 {
+			s.extremum = v
+		}
+	}
+	return nil
+}
+
+// addNumber adds v to the sum. The sum is an integer until a floating point
+// number is added or it overflows.
+func (s *aggregateState) addNumber(agg *Aggregation, v interface{}) error {
+	if sortRank(v) != 2 {
+		return fmt.Errorf("%s(%s): Not a number: %v", agg.Func, agg.Key, v)
+	}
+	if !s.isFloat {
+		if n, err := strconv.ParseInt(toString(v), 10, 64); err == nil {
+			sum := s.intSum + n
+			if (n >= 0 && sum >= s.intSum) || (n < 0 && sum < s.intSum) {
+				s.intSum = sum
+				return nil
+			}
+		}
+		s.isFloat = true
+		s.floatSum = float64(s.intSum)
+	}
+	f, err := toFloat64(v)
+	if err != nil {
+		return fmt.Errorf("%s(%s): Not a number: %v", agg.Func, agg.Key, v)
+	}
+	s.floatSum += f
+	return nil
+}
+
+func (s *aggregateState) result(agg *Aggregation) interface{} {
+	switch agg.Func {
+	case "count":
+		return s.count
+	case "sum":
+		if s.count == 0 {
+			return nil
+		}
+		if s.isFloat {
+			return s.floatSum
+		}
+		return s.intSum
+	case "avg":
+		if s.count == 0 {
+			return nil
+		}
+		sum := s.floatSum
+		if !s.isFloat {
+			sum = float64(s.intSum)
+		}
+		avg := sum / float64(s.count)
+		if math.IsNaN(avg) || math.IsInf(avg, 0) {
+			return nil
+		}
+		return avg
+	default:
+		return s.extremum
+	}
+}
+
+// writeValueKey writes the value with its type, so that values of
+// different types are distinguished, e.g. 1 and "1".
+func writeValueKey(w io.StringWriter, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("n")
+	case bool:
+		w.WriteString("b" + strconv.FormatBool(v))
+	case string:
+		w.WriteString("s" + v)
+	case json.Number:
+		w.WriteString("#" + string(v))
+	default:
+		w.WriteString("v" + toString(v))
+	}
+	w.WriteString("\x00")
+}
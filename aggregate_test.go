@@ -0,0 +1,105 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testAggregateRows() []KeyValue {
+	return []KeyValue{
+		{"/country": "JP", "/amount": json.Number("10"), "/latency": json.Number("1.5")},
+		{"/country": "US", "/amount": json.Number("5"), "/latency": json.Number("3")},
+		{"/country": "JP", "/amount": json.Number("7"), "/latency": nil},
+		{"/country": "US", "/amount": json.Number("2.5")},
+		{"/amount": json.Number("1")},
+	}
+}
+
+var testAggregatorCases = []struct {
+	groupBy  []string
+	aggs     string
+	header   []string
+	expected [][]string
+}{
+	{
+		[]string{"/country"},
+		"count(),sum(/amount),avg(/latency),count(/latency),sum(/latency)",
+		[]string{"/country", "count()", "sum(/amount)", "avg(/latency)", "count(/latency)", "sum(/latency)"},
+		[][]string{
+			{"JP", "2", "17", "1.5", "1", "1.5"},
+			{"US", "2", "7.5", "3", "1", "3"},
+			{"", "1", "1", "", "0", ""},
+		},
+	},
+	{
+		nil,
+		"min(/amount), max(/amount), min(/country)",
+		[]string{"min(/amount)", "max(/amount)", "min(/country)"},
+		[][]string{
+			{"1", "10", "JP"},
+		},
+	},
+}
+
+func TestAggregator(t *testing.T) {
+	for caseIndex, testCase := range testAggregatorCases {
+		aggs, err := ParseAggregations(testCase.aggs)
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		a := NewAggregator(testCase.groupBy, aggs)
+		for _, row := range testAggregateRows() {
+			if err := a.Add(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		header, err := a.Header(JSONPointerStyle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(header, testCase.header) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.header, header)
+		}
+		if actual := a.Records(nil); !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestAggregationName(t *testing.T) {
+	aggs, err := ParseAggregations("count(),sum(/user/age)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := []string{aggs[0].Name(DotNotationStyle), aggs[1].Name(DotNotationStyle)}
+	if expected := []string{"count()", "sum(user.age)"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestInvalidAggregation(t *testing.T) {
+	for caseIndex, spec := range []string{"", "count", "median(/a)", "sum()", "sum(a)"} {
+		if _, err := ParseAggregations(spec); err == nil {
+			t.Errorf("%d: Expected error, but nil", caseIndex)
+		}
+	}
+
+	aggs, _ := ParseAggregations("sum(/country)")
+	a := NewAggregator(nil, aggs)
+	if err := a.Add(testAggregateRows()[0]); err == nil {
+		t.Errorf("Expected error, but nil")
+	}
+}
+
+func TestAggregatorIntegerOverflow(t *testing.T) {
+	aggs, _ := ParseAggregations("sum(/n)")
+	a := NewAggregator(nil, aggs)
+	a.Add(KeyValue{"/n": json.Number("9223372036854775807")})
+	a.Add(KeyValue{"/n": json.Number("1")})
+	if actual := a.Rows()[0][0]; actual != float64(9223372036854775808) {
+		t.Errorf("Expected %v, but %v", float64(9223372036854775808), actual)
+	}
+}
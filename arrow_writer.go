@@ -0,0 +1,241 @@
+package json2csv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// ArrowFormat represents the container format of Apache Arrow IPC.
+type ArrowFormat uint
+
+// Arrow IPC formats
+const (
+	// streaming format (.arrows)
+	ArrowStreamFormat ArrowFormat = iota
+
+	// random access file format (.arrow)
+	ArrowFileFormat
+)
+
+type arrowRecordWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+// ArrowWriter writes Apache Arrow IPC data.
+// Each header key becomes a column, typed according to its ColumnType.
+type ArrowWriter struct {
+	w           io.Writer
+	HeaderStyle KeyStyle
+	Format      ArrowFormat
+
+	// KeyFormatter formats column names instead of HeaderStyle if not nil.
+	KeyFormatter KeyFormatter
+
+	// Renamer renames column names after HeaderStyle is applied.
+	Renamer *Renamer
+
+	keys   []string
+	schema *arrow.Schema
+	writer arrowRecordWriter
+	mem    memory.Allocator
+}
+
+// NewArrowWriter returns new ArrowWriter with given KeyStyle and format.
+func NewArrowWriter(w io.Writer, style KeyStyle, format ArrowFormat) *ArrowWriter {
+	return &ArrowWriter{
+		w:           w,
+		HeaderStyle: style,
+		Format:      format,
+		mem:         memory.NewGoAllocator(),
+	}
+}
+
+// WriteArrow writes data as a single record batch and closes the writer.
+// Column types are inferred from the data.
+func (w *ArrowWriter) WriteArrow(results []KeyValue) error {
+	types := ColumnTypes{}
+	for _, result := range results {
+		types.Update(result)
+	}
+	if err := w.WriteSchema(types); err != nil {
+		return err
+	}
+	if err := w.WriteRecordBatch(results); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// WriteSchema starts the output with the schema built from the given types.
+func (w *ArrowWriter) WriteSchema(types ColumnTypes) error {
+	if w.writer != nil {
+		return errors.New("Arrow schema is already written")
+	}
+
+	pts, err := columnTypesPointers(types)
+	if err != nil {
+		return err
+	}
+	w.keys = pts.Strings()
+	names, err := formatHeader(pts, keyFormatterOf(w.HeaderStyle, w.KeyFormatter), w.Renamer)
+	if err != nil {
+		return err
+	}
+	fields := make([]arrow.Field, 0, len(w.keys))
+	for i, key := range w.keys {
+		fields = append(fields, arrow.Field{
+			Name:     names[i],
+			Type:     arrowDataType(types[key]),
+			Nullable: true,
+		})
+	}
+	w.schema = arrow.NewSchema(fields, nil)
+
+	opts := []ipc.Option{ipc.WithSchema(w.schema), ipc.WithAllocator(w.mem)}
+	switch w.Format {
+	case ArrowStreamFormat:
+		w.writer = ipc.NewWriter(w.w, opts...)
+	case ArrowFileFormat:
+		ws, ok := w.w.(io.WriteSeeker)
+		if !ok {
+			return errors.New("Arrow file format requires a seekable output")
+		}
+		w.writer, err = ipc.NewFileWriter(ws, opts...)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown Arrow format: %d", w.Format)
+	}
+	return nil
+}
+
+// WriteRecordBatch writes the results as a record batch.
+// Fields of results that are absent in the schema are ignored.
+func (w *ArrowWriter) WriteRecordBatch(results []KeyValue) error {
+	if w.writer == nil {
+		return errors.New("Arrow schema is not written yet")
+	}
+
+	b := array.NewRecordBuilder(w.mem, w.schema)
+	defer b.Release()
+	for _, result := range results {
+		for i, key := range w.keys {
+			if err := appendArrowValue(b.Field(i), result[key]); err != nil {
+				return fmt.Errorf("Failed to write %s: %w", key, err)
+			}
+		}
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+	return w.writer.Write(rec)
+}
+
+// Close finishes the output.
+func (w *ArrowWriter) Close() error {
+	if w.writer == nil {
+		return nil
+	}
+	return w.writer.Close()
+}
+
+func columnTypesPointers(types ColumnTypes) (pointers, error) {
+	pts := make(pointers, 0, len(types))
+	for key := range types {
+		pointer, err := jsonpointer.New(key)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, pointer)
+	}
+	sort.Sort(pts)
+	return pts, nil
+}
+
+func arrowDataType(t ColumnType) arrow.DataType {
+	switch t {
+	case NullColumn:
+		return arrow.Null
+	case IntegerColumn:
+		return arrow.PrimitiveTypes.Int64
+	case FloatColumn:
+		return arrow.PrimitiveTypes.Float64
+	case BoolColumn:
+		return arrow.FixedWidthTypes.Boolean
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func appendArrowValue(b array.Builder, value interface{}) error {
+	if value == nil {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.NullBuilder:
+		b.AppendNull()
+	case *array.Int64Builder:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := toFloat64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("Not a boolean: %v", value)
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		b.Append(toString(value))
+	default:
+		return fmt.Errorf("Unsupported Arrow builder: %T", b)
+	}
+	return nil
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("Integer overflow: %d", v)
+		}
+		return int64(v), nil
+	default:
+		return strconv.ParseInt(toString(value), 10, 64)
+	}
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	default:
+		return strconv.ParseFloat(toString(value), 64)
+	}
+}
@@ -0,0 +1,117 @@
+package json2csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+)
+
+// readArrowStream reads all record batches and returns the schema and
+// each column as a string representation.
+func readArrowStream(t *testing.T, b []byte) (*arrow.Schema, int, map[string]string) {
+	r, err := ipc.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+
+	batches := 0
+	columns := map[string]string{}
+	for r.Next() {
+		rec := r.Record()
+		for i, field := range rec.Schema().Fields() {
+			columns[field.Name] += rec.Column(i).String()
+		}
+		batches++
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return r.Schema(), batches, columns
+}
+
+func TestWriteArrow(t *testing.T) {
+	obj, err := json2obj(`[
+		{"id": 1, "name": "foo", "score": 1.5, "active": true},
+		{"id": 2, "name": "bar", "score": 2, "mixed": 1},
+		{"id": 3, "active": false, "mixed": "x"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := JSON2CSV(obj, nil, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	if err := NewArrowWriter(b, DotNotationStyle, ArrowStreamFormat).WriteArrow(results); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, batches, columns := readArrowStream(t, b.Bytes())
+	if batches != 1 {
+		t.Errorf("Expected 1 batch, but %d", batches)
+	}
+	expectedTypes := map[string]arrow.DataType{
+		"active": arrow.FixedWidthTypes.Boolean,
+		"id":     arrow.PrimitiveTypes.Int64,
+		"mixed":  arrow.BinaryTypes.String,
+		"name":   arrow.BinaryTypes.String,
+		"score":  arrow.PrimitiveTypes.Float64,
+	}
+	for _, field := range schema.Fields() {
+		if !arrow.TypeEqual(field.Type, expectedTypes[field.Name]) {
+			t.Errorf("%s: Expected %v, but %v", field.Name, expectedTypes[field.Name], field.Type)
+		}
+	}
+	expectedColumns := map[string]string{
+		"active": `[true (null) false]`,
+		"id":     `[1 2 3]`,
+		"mixed":  `[(null) "1" "x"]`,
+		"name":   `["foo" "bar" (null)]`,
+		"score":  `[1.5 2 (null)]`,
+	}
+	if !reflect.DeepEqual(expectedColumns, columns) {
+		t.Errorf("Expected %v, but %v", expectedColumns, columns)
+	}
+}
+
+func TestJSON2ArrowOnline(t *testing.T) {
+	zipReader, err := zip.OpenReader(createTestZip(t, testStreamJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zipReader.Close()
+
+	types, err := JSON2ColumnTypes(NewJSONStreamZipReader(zipReader), "", NewFlattenOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	err = JSON2ArrowOnline(NewJSONStreamZipReader(zipReader), types, b, JSONPointerStyle, ArrowStreamFormat, "", NewFlattenOptions(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, batches, columns := readArrowStream(t, b.Bytes())
+	if batches != 2 {
+		t.Errorf("Expected 2 batches, but %d", batches)
+	}
+	expectedColumns := map[string]string{
+		"/active": `[true (null)][false]`,
+		"/id":     `[1 2][3]`,
+		"/name":   `["foo" "bar"][(null)]`,
+		"/score":  `[1.5 (null)][2]`,
+		"/tags/0": `[(null) "a"][(null)]`,
+		"/tags/1": `[(null) "b"][(null)]`,
+	}
+	if !reflect.DeepEqual(expectedColumns, columns) {
+		t.Errorf("Expected %v, but %v", expectedColumns, columns)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+
+	"github.com/urfave/cli"
+)
+
+var aggregateFormats = map[string]bool{
+	"csv":   true,
+	"table": true,
+}
+
+var aggregateCommand = cli.Command{
+	Name:      "aggregate",
+	Usage:     "aggregate rows grouped by columns",
+	ArgsUsage: "[FILE]",
+	Flags: append(append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "group-by",
+			Usage: "comma separated columns to group rows by (e.g. /country,/city)",
+		},
+		cli.StringFlag{
+			Name:  "agg",
+			Value: "count()",
+			Usage: "comma separated aggregations: count(), count(/key), sum, avg, min, max (e.g. 'count(),sum(/amount)')",
+		},
+		cli.StringFlag{
+			Name:  "output-format",
+			Value: "csv",
+			Usage: "output format (csv, table)",
+		},
+	}, inputFlags...), append(valueFormatFlags, outputFlags...)...),
+	Before: func(c *cli.Context) error {
+		if err := applyProfile(c); err != nil {
+			return err
+		}
+		if _, err := keyFormatter(c.String("header-style")); err != nil {
+			return err
+		}
+		if _, ok := boolStyleTable[c.String("bool-style")]; !ok {
+			return fmt.Errorf("Invalid --bool-style value %q", c.String("bool-style"))
+		}
+		if c.Int("workers") < 0 {
+			return fmt.Errorf("Invalid --workers value %d", c.Int("workers"))
+		}
+		if !aggregateFormats[c.String("output-format")] {
+			return fmt.Errorf("Invalid --output-format value %q", c.String("output-format"))
+		}
+		_, err := aggregator(c)
+		return err
+	},
+	Action: func(c *cli.Context) error {
+		return aggregateAction(c)
+	},
+}
+
+func aggregator(c *cli.Context) (*json2csv.Aggregator, error) {
+	var groupBy []string
+	if c.String("group-by") != "" {
+		var err error
+		groupBy, err = json2csv.ParseUniqueKeys(c.String("group-by"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --group-by value %q: %w", c.String("group-by"), err)
+		}
+	}
+	aggs, err := json2csv.ParseAggregations(c.String("agg"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --agg value %q: %w", c.String("agg"), err)
+	}
+	return json2csv.NewAggregator(groupBy, aggs), nil
+}
+
+func aggregateAction(c *cli.Context) error {
+	opts, err := flattenOptions(c)
+	if err != nil {
+		return err
+	}
+	agg, err := aggregator(c)
+	if err != nil {
+		return err
+	}
+	if c.NArg() > 0 && c.Args()[0] != "-" && c.Bool("stream") {
+		if err := eachStreamRow(c.Args()[0], c, opts, agg.Add); err != nil {
+			return err
+		}
+	} else {
+		var data interface{}
+		if c.NArg() > 0 && c.Args()[0] != "-" {
+			data, err = readJSONFile(c.Args()[0])
+		} else {
+			data, err = readJSON(os.Stdin)
+		}
+		if err != nil {
+			return err
+		}
+		if c.String("path") != "" {
+			data, err = jsonpointer.Get(data, c.String("path"))
+			if err != nil {
+				return err
+			}
+		}
+		results, err := json2csv.JSON2CSVWithOptions(data, nil, opts)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if err := agg.Add(result); err != nil {
+				return err
+			}
+		}
+	}
+
+	headerStyle, err := keyFormatter(c.String("header-style"))
+	if err != nil {
+		return err
+	}
+	header, err := agg.Header(headerStyle)
+	if err != nil {
+		return err
+	}
+	records := agg.Records(valueFormatter(c))
+	out, err := openOutput(c)
+	if err != nil {
+		return err
+	}
+	return out.Close(writeRecords(out, c.String("output-format"), header, records))
+}
+
+func writeRecords(out *output, format string, header []string, records [][]string) error {
+	if format == "table" {
+		table := json2csv.NewTableWriter(out, json2csv.JSONPointerStyle, false)
+		table.Width = out.width()
+		return table.WriteRecords(header, records)
+	}
+	w := json2csv.NewCSVWriter(out, json2csv.JSONPointerStyle, false)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return w.Error()
+}
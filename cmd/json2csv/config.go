@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles are the names of the config file, searched in the current
+// directory and then the home directory.
+var configFiles = []string{".json2csv.yaml", ".json2csv.yml", ".json2csv.json"}
+
+// config is the config file. Each profile maps option names to values,
+// which are lists for options that can be specified multiple times.
+type config struct {
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+var configFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "config",
+		Usage: "config file of --profile (default: .json2csv.yaml in the current or home directory)",
+	},
+	cli.StringFlag{
+		Name:  "profile",
+		Usage: "apply options of the profile in the config file, which are overridden by the command line and environment variables",
+	},
+}
+
+// withEnvVars sets the environment variable of each flag, like
+// JSON2CSV_HEADER_STYLE for --header-style.
+func withEnvVars(flags []cli.Flag) []cli.Flag {
+	result := make([]cli.Flag, 0, len(flags))
+	for _, flag := range flags {
+		env := envVar(flagName(flag))
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.EnvVar = env
+			flag = f
+		case cli.IntFlag:
+			f.EnvVar = env
+			flag = f
+		case cli.BoolFlag:
+			if flagName(f) != flagName(cli.HelpFlag) {
+				f.EnvVar = env
+			}
+			flag = f
+		case cli.StringSliceFlag:
+			f.EnvVar = env
+			flag = f
+		}
+		result = append(result, flag)
+	}
+	return result
+}
+
+func envVar(name string) string {
+	return "JSON2CSV_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyProfile sets the options of --profile which are not set by the
+// command line or environment variables.
+func applyProfile(c *cli.Context) error {
+	name := c.GlobalString("profile")
+	if name == "" {
+		return nil
+	}
+	profile, err := loadProfile(c.GlobalString("config"), name)
+	if err != nil {
+		return err
+	}
+
+	known := knownFlags(c.App)
+	flags := map[string]bool{}
+	for _, flag := range c.Command.Flags {
+		flags[flagName(flag)] = true
+	}
+	if c.Command.Name == "" {
+		for _, flag := range c.App.Flags {
+			flags[flagName(flag)] = true
+		}
+	}
+
+	keys := make([]string, 0, len(profile))
+	for key := range profile {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] || key == "config" || key == "profile" {
+			return fmt.Errorf("Unknown option %q in profile %q", key, name)
+		}
+		if !flags[key] || c.IsSet(key) {
+			continue
+		}
+		values, err := profileValues(profile[key])
+		if err != nil {
+			return fmt.Errorf("Invalid option %q in profile %q: %w", key, name, err)
+		}
+		for _, value := range values {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("Invalid option %q in profile %q: %w", key, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadProfile reads the profile from the config file, which is searched if
+// filename is empty.
+func loadProfile(filename string, name string) (map[string]interface{}, error) {
+	if filename == "" {
+		filename = findConfigFile()
+		if filename == "" {
+			return nil, fmt.Errorf("Config file of profile %q is not found", name)
+		}
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var conf config
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %w", filename, err)
+	}
+	profile, ok := conf.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("Profile %q is not found in %s", name, filename)
+	}
+	return profile, nil
+}
+
+func findConfigFile() string {
+	dirs := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	for _, dir := range dirs {
+		for _, name := range configFiles {
+			filename := filepath.Join(dir, name)
+			if _, err := os.Stat(filename); err == nil {
+				return filename
+			}
+		}
+	}
+	return ""
+}
+
+// profileValues returns the value of the option as flag values.
+func profileValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, err := profileValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		s, err := profileValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+func profileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, bool, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("Unsupported value %v", value)
+}
+
+// knownFlags returns the names of the flags of the app and the commands.
+func knownFlags(app *cli.App) map[string]bool {
+	known := map[string]bool{}
+	for _, flag := range app.Flags {
+		known[flagName(flag)] = true
+	}
+	for _, command := range app.Commands {
+		for _, flag := range command.Flags {
+			known[flagName(flag)] = true
+		}
+	}
+	return known
+}
+
+// flagName returns the long name of the flag.
+func flagName(flag cli.Flag) string {
+	return strings.TrimSpace(strings.Split(flag.GetName(), ",")[0])
+}
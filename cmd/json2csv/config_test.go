@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+var testProfileValuesCases = []struct {
+	value    interface{}
+	expected []string
+	ok       bool
+}{
+	{"dot", []string{"dot"}, true},
+	{2, []string{"2"}, true},
+	{1.5, []string{"1.5"}, true},
+	{true, []string{"true"}, true},
+	{[]interface{}{"a=1", 2, false}, []string{"a=1", "2", "false"}, true},
+	{[]interface{}{}, []string{}, true},
+	{nil, nil, false},
+	{map[string]interface{}{"a": 1}, nil, false},
+	{[]interface{}{"a", []interface{}{1}}, nil, false},
+	{[]interface{}{map[string]interface{}{"a": 1}}, nil, false},
+}
+
+func TestProfileValues(t *testing.T) {
+	for caseIndex, testCase := range testProfileValuesCases {
+		actual, err := profileValues(testCase.value)
+		if ok := err == nil; ok != testCase.ok {
+			t.Errorf("%d: Expected %v, but %v (%v)", caseIndex, testCase.ok, ok, err)
+			continue
+		}
+		if testCase.ok && !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+// chdir changes the working directory during the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeTestFile(t *testing.T, filename, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+var testLoadProfileCases = []struct {
+	files    map[string]string // files in the working directory
+	home     map[string]string // files in the home directory
+	filename string
+	name     string
+	expected map[string]interface{}
+}{
+	{
+		map[string]string{".json2csv.json": `{"profiles": {"p": {"max-depth": 1, "add-column": ["a=1"]}}}`},
+		nil, "", "p",
+		map[string]interface{}{"max-depth": 1, "add-column": []interface{}{"a=1"}},
+	},
+	{
+		map[string]string{".json2csv.yaml": "profiles:\n  p:\n    transpose: true\n", ".json2csv.json": `{"profiles": {"p": {}}}`},
+		nil, "", "p",
+		map[string]interface{}{"transpose": true},
+	},
+	{
+		nil,
+		map[string]string{".json2csv.yml": "profiles:\n  p:\n    header-style: dot\n"},
+		"", "p",
+		map[string]interface{}{"header-style": "dot"},
+	},
+	{
+		map[string]string{"conf.yaml": "profiles:\n  p:\n    stream: true\n"},
+		map[string]string{".json2csv.yaml": "profiles:\n  p:\n    transpose: true\n"},
+		"conf.yaml", "p",
+		map[string]interface{}{"stream": true},
+	},
+	{map[string]string{".json2csv.json": `{"profiles": {"p": {}}}`}, nil, "", "q", nil},
+	{map[string]string{".json2csv.yaml": "profiles: [\n"}, nil, "", "p", nil},
+	{nil, nil, "", "p", nil},
+	{nil, nil, "missing.yaml", "p", nil},
+}
+
+func TestLoadProfile(t *testing.T) {
+	for caseIndex, testCase := range testLoadProfileCases {
+		dir, home := t.TempDir(), t.TempDir()
+		for name, content := range testCase.files {
+			writeTestFile(t, filepath.Join(dir, name), content)
+		}
+		for name, content := range testCase.home {
+			writeTestFile(t, filepath.Join(home, name), content)
+		}
+		chdir(t, dir)
+		t.Setenv("HOME", home)
+
+		actual, err := loadProfile(testCase.filename, testCase.name)
+		if testCase.expected == nil {
+			if err == nil {
+				t.Errorf("%d: Expected an error, but %v", caseIndex, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+const testConfig = `
+profiles:
+  p:
+    header-style: dot
+    max-depth: 2
+    transpose: true
+    add-column:
+      - a=1
+      - b=2
+    format: table
+  bad:
+    no-such-option: 1
+  invalid:
+    max-depth: x
+`
+
+// newTestApp returns an app with global flags and a subcommand, which
+// records the values of the flags to values.
+func newTestApp(values map[string]string) *cli.App {
+	record := func(names ...string) func(c *cli.Context) error {
+		return func(c *cli.Context) error {
+			for _, name := range names {
+				values[name] = fmt.Sprint(c.Generic(name))
+			}
+			return nil
+		}
+	}
+
+	app := cli.NewApp()
+	app.Writer = io.Discard
+	app.ErrWriter = io.Discard
+	app.Flags = withEnvVars(append([]cli.Flag{
+		cli.StringFlag{Name: "header-style", Value: "jsonpointer"},
+		cli.IntFlag{Name: "max-depth"},
+		cli.BoolFlag{Name: "transpose"},
+		cli.StringSliceFlag{Name: "add-column"},
+	}, configFlags...))
+	app.Commands = []cli.Command{
+		{
+			Name: "schema",
+			Flags: withEnvVars([]cli.Flag{
+				cli.StringFlag{Name: "format", Value: "json"},
+				cli.IntFlag{Name: "max-depth"},
+			}),
+			Before: applyProfile,
+			Action: record("format", "max-depth"),
+		},
+	}
+	app.Before = applyProfile
+	app.Action = record("header-style", "max-depth", "transpose", "add-column")
+	return app
+}
+
+var testApplyProfileCases = []struct {
+	args     []string
+	env      map[string]string
+	expected map[string]string // nil if an error is expected
+}{
+	{
+		[]string{},
+		nil,
+		map[string]string{"header-style": "jsonpointer", "max-depth": "0", "transpose": "false", "add-column": "[]"},
+	},
+	{
+		[]string{"--profile=p"},
+		nil,
+		map[string]string{"header-style": "dot", "max-depth": "2", "transpose": "true", "add-column": "[a=1 b=2]"},
+	},
+	{
+		[]string{},
+		map[string]string{"JSON2CSV_PROFILE": "p"},
+		map[string]string{"header-style": "dot", "max-depth": "2", "transpose": "true", "add-column": "[a=1 b=2]"},
+	},
+	{
+		[]string{"--profile=p"},
+		map[string]string{"JSON2CSV_MAX_DEPTH": "3", "JSON2CSV_ADD_COLUMN": "c=3"},
+		map[string]string{"header-style": "dot", "max-depth": "3", "transpose": "true", "add-column": "[c=3]"},
+	},
+	{
+		[]string{"--profile=p", "--max-depth=4", "--transpose=false", "--header-style=slash"},
+		map[string]string{"JSON2CSV_MAX_DEPTH": "3"},
+		map[string]string{"header-style": "slash", "max-depth": "4", "transpose": "false", "add-column": "[a=1 b=2]"},
+	},
+	{
+		[]string{"--profile=p", "schema"},
+		nil,
+		map[string]string{"format": "table", "max-depth": "2"},
+	},
+	{
+		[]string{"--profile=p", "schema", "--format=json"},
+		map[string]string{"JSON2CSV_MAX_DEPTH": "5"},
+		map[string]string{"format": "json", "max-depth": "5"},
+	},
+	{[]string{"--profile=bad"}, nil, nil},
+	{[]string{"--profile=bad", "schema"}, nil, nil},
+	{[]string{"--profile=invalid"}, nil, nil},
+	{[]string{"--profile=missing"}, nil, nil},
+}
+
+func TestApplyProfile(t *testing.T) {
+	config := writeTestFile(t, filepath.Join(t.TempDir(), "config.yaml"), testConfig)
+	for caseIndex, testCase := range testApplyProfileCases {
+		for _, name := range []string{"JSON2CSV_PROFILE", "JSON2CSV_MAX_DEPTH", "JSON2CSV_ADD_COLUMN"} {
+			t.Setenv(name, testCase.env[name])
+			if _, ok := testCase.env[name]; !ok {
+				os.Unsetenv(name)
+			}
+		}
+
+		values := map[string]string{}
+		args := append([]string{"json2csv", "--config=" + config}, testCase.args...)
+		err := newTestApp(values).Run(args)
+		if testCase.expected == nil {
+			if err == nil {
+				t.Errorf("%d: Expected an error, but %v", caseIndex, values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, values)
+		}
+	}
+}
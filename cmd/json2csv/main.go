@@ -2,18 +2,22 @@ package main
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/yukithm/json2csv"
 	"github.com/yukithm/json2csv/jsonpointer"
 
 	"github.com/urfave/cli"
+	"golang.org/x/term"
 )
 
 const (
@@ -29,6 +33,62 @@ var headerStyleTable = map[string]json2csv.KeyStyle{
 	"slash":       json2csv.SlashStyle,
 	"dot":         json2csv.DotNotationStyle,
 	"dot-bracket": json2csv.DotBracketStyle,
+	"snake":       json2csv.SnakeCaseStyle,
+	"camel":       json2csv.CamelCaseStyle,
+}
+
+// valueFormatFlags are the flags of valueFormatter.
+var valueFormatFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "decimals",
+		Value: -1,
+		Usage: "number of digits after the decimal point of floating point numbers, leaving integers unchanged (-1: as is)",
+	},
+	cli.BoolFlag{
+		Name:  "no-exponent",
+		Usage: "don't use scientific notation for numbers",
+	},
+	cli.StringFlag{
+		Name:  "bool-style",
+		Value: "true-false",
+		Usage: "boolean style (true-false, TRUE-FALSE, 1-0, yes-no)",
+	},
+	cli.StringFlag{
+		Name:  "decimal-separator",
+		Value: ".",
+		Usage: "decimal separator of numbers",
+	},
+}
+
+var keepTable = map[string]bool{
+	"first": false,
+	"last":  true,
+}
+
+var arrayPolicyTable = map[string]json2csv.ArrayPolicy{
+	"index": json2csv.IndexArray,
+	"join":  json2csv.JoinArray,
+	"json":  json2csv.JSONArray,
+	"count": json2csv.CountArray,
+}
+
+var boolStyleTable = map[string]json2csv.BoolStyle{
+	"true-false": json2csv.TrueFalseBool,
+	"TRUE-FALSE": json2csv.UpperTrueFalseBool,
+	"1-0":        json2csv.OneZeroBool,
+	"yes-no":     json2csv.YesNoBool,
+}
+
+var outputFormats = map[string]bool{
+	"csv":        true,
+	"table":      true,
+	"arrow":      true,
+	"arrow-file": true,
+}
+
+var arrowFormatTable = map[string]json2csv.ArrowFormat{
+	"arrow":      json2csv.ArrowStreamFormat,
+	"arrow-file": json2csv.ArrowFileFormat,
 }
 
 func main() {
@@ -67,11 +127,15 @@ COPYRIGHT:
 	app.Usage = "convert JSON to CSV"
 	app.ArgsUsage = "[FILE]"
 	app.HideHelp = true
+	app.Commands = []cli.Command{
+		schemaCommand,
+		aggregateCommand,
+	}
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:  "header-style",
 			Value: "jsonpointer",
-			Usage: "header style (jsonpointer, slash, dot, dot-bracket)",
+			Usage: "header style (jsonpointer, slash, dot, dot-bracket, snake, camel) or template (e.g. '{{join . \"__\"}}')",
 		},
 		cli.StringFlag{
 			Name:  "path",
@@ -82,6 +146,106 @@ COPYRIGHT:
 			Value: math.MaxInt,
 			Usage: "Specify the length of the slice to be processed.",
 		},
+		cli.StringFlag{
+			Name:  "where",
+			Usage: "convert only records matching the expression (e.g. '/age >= 18 && exists(/email)')",
+		},
+		cli.StringSliceFlag{
+			Name:  "add-column",
+			Usage: "add a column computed from each row (e.g. 'total=/price * /qty')",
+		},
+		cli.IntFlag{
+			Name:  "max-depth",
+			Usage: "write objects and arrays deeper than this as JSON strings (0: unlimited)",
+		},
+		cli.StringSliceFlag{
+			Name:  "max-depth-at",
+			Usage: "override --max-depth under the path, relative to it (e.g. /payload=1)",
+		},
+		cli.StringFlag{
+			Name:  "array-policy",
+			Value: "index",
+			Usage: "how arrays are flattened (index, join, json, count)",
+		},
+		cli.StringSliceFlag{
+			Name:  "array-policy-at",
+			Usage: "override --array-policy for arrays at the path (e.g. /tags=join)",
+		},
+		cli.StringFlag{
+			Name:  "array-separator",
+			Value: json2csv.DefaultArraySeparator,
+			Usage: "separator of joined arrays",
+		},
+		cli.StringFlag{
+			Name:  "melt",
+			Usage: "convert columns under the path into rows of --key-column and --value-column (e.g. /metrics)",
+		},
+		cli.StringFlag{
+			Name:  "key-column",
+			Value: json2csv.DefaultKeyColumn,
+			Usage: "column of keys of --melt and --pivot",
+		},
+		cli.StringFlag{
+			Name:  "value-column",
+			Value: json2csv.DefaultValueColumn,
+			Usage: "column of values of --melt and --pivot",
+		},
+		cli.StringFlag{
+			Name:  "pivot",
+			Usage: "convert rows of --key-column and --value-column into columns under the path, the reverse of --melt",
+		},
+		cli.StringFlag{
+			Name:  "split-by",
+			Usage: "write rows into a file for each value of the column (e.g. /region)",
+		},
+		cli.IntFlag{
+			Name:  "max-rows",
+			Usage: "write rows into a new file every this number of rows (0: unlimited)",
+		},
+		cli.StringFlag{
+			Name:  "split-prefix",
+			Value: "out",
+			Usage: "prefix of the files of --split-by and --max-rows (PREFIX-VALUE.csv, PREFIX-1.csv)",
+		},
+		cli.BoolFlag{
+			Name:  "shared-header",
+			Usage: "write the header of all rows into every file of --split-by and --max-rows",
+		},
+		cli.StringFlag{
+			Name:  "rename",
+			Usage: "rename header keys with the mapping `FILE` (lines of \"POINTER = NAME\")",
+		},
+		cli.StringFlag{
+			Name:  "unique-by",
+			Usage: "remove rows that have the same values of comma separated columns (e.g. /event_id)",
+		},
+		cli.BoolFlag{
+			Name:  "unique",
+			Usage: "remove duplicate rows",
+		},
+		cli.StringFlag{
+			Name:  "keep",
+			Value: "first",
+			Usage: "which row of duplicates to keep with --unique-by or --unique (first, last)",
+		},
+		cli.IntFlag{
+			Name:  "unique-memory-keys",
+			Value: json2csv.DefaultMaxMemoryKeys,
+			Usage: "keys held in memory to find duplicates, more keys are indexed in temporary files",
+		},
+		cli.StringFlag{
+			Name:  "sort-by",
+			Usage: "sort rows by comma separated columns, \"-\" prefix for descending order (e.g. /created_at,-/id)",
+		},
+		cli.BoolFlag{
+			Name:  "nulls-last",
+			Usage: "sort null and missing values last",
+		},
+		cli.IntFlag{
+			Name:  "sort-chunk-size",
+			Value: json2csv.DefaultSortChunkSize,
+			Usage: "rows sorted in memory in stream mode, larger data is merged via temporary files",
+		},
 		cli.BoolFlag{
 			Name:  "transpose",
 			Usage: "transpose rows and columns",
@@ -90,12 +254,104 @@ COPYRIGHT:
 			Name:  "stream",
 			Usage: "convert data stream",
 		},
-		cli.HelpFlag,
+		cli.IntFlag{
+			Name:  "workers",
+			Value: 1,
+			Usage: "goroutines to decode and flatten records in stream mode, output order is kept (0: number of CPUs)",
+		},
+		cli.BoolFlag{
+			Name:  "progress",
+			Usage: "show progress of each pass over the input in stream mode on stderr",
+		},
+		cli.StringFlag{
+			Name:  "output-format",
+			Value: "csv",
+			Usage: "output format (csv, table, arrow, arrow-file)",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: 1024,
+			Usage: "rows per record batch of arrow formats in stream mode",
+		},
+		cli.IntFlag{
+			Name:  "max-column-width",
+			Value: 40,
+			Usage: "truncate cells wider than this in table format (0: unlimited)",
+		},
+	}
+	app.Flags = append(app.Flags, valueFormatFlags...)
+	app.Flags = append(app.Flags, outputFlags...)
+	app.Flags = append(app.Flags, configFlags...)
+	app.Flags = append(app.Flags, cli.HelpFlag)
+	app.Flags = withEnvVars(app.Flags)
+	for i := range app.Commands {
+		app.Commands[i].Flags = withEnvVars(app.Commands[i].Flags)
 	}
 
 	app.Before = func(c *cli.Context) error {
-		if _, ok := headerStyleTable[c.String("header-style")]; !ok {
-			return fmt.Errorf("Invalid --header-style value %q", c.String("header-style"))
+		if err := applyProfile(c); err != nil {
+			return err
+		}
+		if _, err := keyFormatter(c.String("header-style")); err != nil {
+			return err
+		}
+		if _, ok := boolStyleTable[c.String("bool-style")]; !ok {
+			return fmt.Errorf("Invalid --bool-style value %q", c.String("bool-style"))
+		}
+		if !outputFormats[c.String("output-format")] {
+			return fmt.Errorf("Invalid --output-format value %q", c.String("output-format"))
+		}
+		if c.Int("workers") < 0 {
+			return fmt.Errorf("Invalid --workers value %d", c.Int("workers"))
+		}
+		if c.Int("batch-size") <= 0 {
+			return fmt.Errorf("Invalid --batch-size value %d", c.Int("batch-size"))
+		}
+		if c.String("output-format") == "table" && c.Bool("stream") {
+			return fmt.Errorf("--output-format=table cannot be used with --stream")
+		}
+		if err := checkSeekable(c); err != nil {
+			return err
+		}
+		if _, err := rowSorter(c); err != nil {
+			return err
+		}
+		if _, err := deduplicator(c); err != nil {
+			return err
+		}
+		if _, err := pivot(c); err != nil {
+			return err
+		}
+		if c.String("pivot") != "" && c.Bool("stream") {
+			return fmt.Errorf("--pivot cannot be used with --stream")
+		}
+		if c.Int("max-rows") < 0 {
+			return fmt.Errorf("Invalid --max-rows value %d", c.Int("max-rows"))
+		}
+		if c.String("split-by") != "" {
+			if _, err := jsonpointer.New(c.String("split-by")); err != nil {
+				return fmt.Errorf("Invalid --split-by value %q", c.String("split-by"))
+			}
+		}
+		if splitting(c) && (c.String("output-format") != "csv" || c.Bool("transpose")) {
+			return fmt.Errorf("--split-by and --max-rows can be used only with --output-format=csv without --transpose")
+		}
+		if splitting(c) && c.String("output") != "" {
+			return fmt.Errorf("--output cannot be used with --split-by and --max-rows, use --split-prefix instead")
+		}
+		if c.Int("unique-memory-keys") <= 0 {
+			return fmt.Errorf("Invalid --unique-memory-keys value %d", c.Int("unique-memory-keys"))
+		}
+		if c.Int("sort-chunk-size") <= 0 {
+			return fmt.Errorf("Invalid --sort-chunk-size value %d", c.Int("sort-chunk-size"))
+		}
+		if _, ok := arrowFormatTable[c.String("output-format")]; ok && c.Bool("stream") {
+			if c.String("sort-by") != "" {
+				return fmt.Errorf("--sort-by cannot be used with --stream and --output-format=%s", c.String("output-format"))
+			}
+			if c.String("unique-by") != "" || c.Bool("unique") {
+				return fmt.Errorf("--unique-by and --unique cannot be used with --stream and --output-format=%s", c.String("output-format"))
+			}
 		}
 		return nil
 	}
@@ -111,49 +367,64 @@ COPYRIGHT:
 	app.RunAndExitOnError()
 }
 
-func streamReaderFromFile(filename string) json2csv.JSONStreamReader {
+func streamReaderFromFile(filename string) (json2csv.JSONStreamReader, error) {
 	var reader json2csv.JSONStreamReader
 	if strings.HasSuffix(filename, ".zip") {
 		zipReader, err := zip.OpenReader(filename)
 		if err != nil {
-			log.Fatal(err)
-			return nil
+			return nil, err
 		}
 		// defer zipReader.Close()
 		reader = json2csv.NewJSONStreamZipReader(zipReader)
 	} else {
 		file, err := os.Open(filename)
 		if err != nil {
-			log.Fatal(err)
-			return nil
+			return nil, err
 		}
 		// defer file.Close()
 		reader = json2csv.NewJSONStreamLineReader(file)
 
 	}
-	return reader
+	return reader, nil
 }
 
 func mainAction(c *cli.Context) {
 	var data interface{}
 	var err error
-	headerStyle := headerStyleTable[c.String("header-style")]
-	sliceLen := c.Int("slice-len")
+	headerStyle, err := keyFormatter(c.String("header-style"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts, err := flattenOptions(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	renamer, err := loadRenamer(c.String("rename"))
+	if err != nil {
+		log.Fatal(err)
+	}
 	if c.NArg() > 0 && c.Args()[0] != "-" {
 		filename := c.Args()[0]
 		if c.Bool("stream") {
-			reader := streamReaderFromFile(filename)
-			csvHeader, err := json2csv.JSON2CSVHeader(reader, c.String("path"), sliceLen)
+			// The input is opened before the output so that a missing input
+			// leaves no file behind.
+			reader, err := streamReaderFromFile(filename)
 			if err != nil {
 				log.Fatal(err)
 			}
 			reader.Close()
-			reader = streamReaderFromFile(filename)
-			err = json2csv.JSON2CSVOnline(reader, csvHeader, os.Stdout, headerStyle, false, c.String("path"), sliceLen)
-			reader.Close()
+			out, err := openOutput(c)
 			if err != nil {
 				log.Fatal(err)
 			}
+			if format, ok := arrowFormatTable[c.String("output-format")]; ok {
+				err = streamArrow(out, filename, c, headerStyle, format, opts, renamer)
+			} else {
+				err = streamCSV(out, filename, c, headerStyle, opts, renamer)
+			}
+			if err := out.Close(err); err != nil {
+				log.Fatal(err)
+			}
 			return
 		}
 		data, err = readJSONFile(filename)
@@ -171,18 +442,404 @@ func mainAction(c *cli.Context) {
 		}
 	}
 
-	results, err := json2csv.JSON2CSV(data, nil, sliceLen)
+	results, err := json2csv.JSON2CSVWithOptions(data, nil, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if len(results) == 0 {
-		return
+	if p, _ := pivot(c); p != nil {
+		results, err = p.Apply(results)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if dedup, _ := deduplicator(c); dedup != nil {
+		results, err = dedup.Unique(results)
+		dedup.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if sorter, _ := rowSorter(c); sorter != nil {
+		sorter.Sort(results)
 	}
 
-	err = printCSV(os.Stdout, results, headerStyle, c.Bool("transpose"))
+	out, err := openOutput(c)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if len(results) > 0 {
+		err = writeResults(out, c, results, headerStyle, renamer)
+	}
+	if err := out.Close(err); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeResults(out *output, c *cli.Context, results []json2csv.KeyValue, headerStyle json2csv.KeyFormatter, renamer *json2csv.Renamer) error {
+	if format, ok := arrowFormatTable[c.String("output-format")]; ok {
+		writer := json2csv.NewArrowWriter(out, json2csv.JSONPointerStyle, format)
+		writer.KeyFormatter = headerStyle
+		writer.Renamer = renamer
+		return writer.WriteArrow(results)
+	} else if splitting(c) {
+		return writePartitions(c, results, headerStyle, renamer)
+	} else if c.String("output-format") == "table" {
+		return printTable(out, results, headerStyle, c.Bool("transpose"), c.Int("max-column-width"), valueFormatter(c), renamer)
+	}
+	return printCSV(out, results, headerStyle, c.Bool("transpose"), valueFormatter(c), renamer)
+}
+
+func streamCSV(out *output, filename string, c *cli.Context, headerStyle json2csv.KeyFormatter, opts *json2csv.FlattenOptions, renamer *json2csv.Renamer) (err error) {
+	csvHeader := json2csv.CSVHeader{}
+	err = eachStreamRow(filename, c, opts, func(row json2csv.KeyValue) error {
+		for key := range row {
+			csvHeader[key] = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writer := csvWriter(out, c, headerStyle, renamer)
+	writer.Transpose = c.Bool("transpose")
+
+	dedup, _ := deduplicator(c)
+	sorter, _ := rowSorter(c)
+	if dedup == nil && !splitting(c) {
+		return eachStreamPass(filename, c, func(reader json2csv.JSONStreamReader, bar *progressBar) error {
+			o := streamOptions(c, opts, bar)
+			o.KeyFormatter = headerStyle
+			o.Renamer = renamer
+			o.Transpose = c.Bool("transpose")
+			o.ValueFormatter = valueFormatter(c)
+			o.Sort = sorter
+			o.SortChunkSize = c.Int("sort-chunk-size")
+			converter, err := json2csv.NewConverter(o)
+			if err != nil {
+				return err
+			}
+			return converter.ConvertStream(context.Background(), reader, csvHeader, out)
+		})
+	}
+
+	var writeRow func(row json2csv.KeyValue) error
+	var transposer *json2csv.Transposer
+	if splitting(c) {
+		// The order and the number of rows of each value are unknown until
+		// they are written, so every partition of a value has the header of
+		// all rows of the value.
+		header := func(name, value string) json2csv.CSVHeader { return csvHeader }
+		if c.String("split-by") != "" && !c.Bool("shared-header") {
+			headers, err := json2csv.PartitionHeaders(json2csv.NewPartitioner(c.String("split-by"), 0), func(fn func(row json2csv.KeyValue) error) error {
+				return eachStreamRow(filename, c, opts, fn)
+			})
+			if err != nil {
+				return err
+			}
+			header = func(name, value string) json2csv.CSVHeader { return headers[value] }
+		}
+		w, discard := partitionWriter(c, headerStyle, renamer, header)
+		defer func() {
+			if err != nil {
+				discard()
+				return
+			}
+			err = w.Close()
+		}()
+		writeRow = w.Write
+	} else if writer.Transpose {
+		header, err := writer.CompileHeader(csvHeader)
+		if err != nil {
+			return err
+		}
+		transposer = writer.NewTransposer(header)
+		defer transposer.Close()
+		writeRow = transposer.Write
+	} else {
+		var header *json2csv.Header
+		header, err = writer.CompileHeader(csvHeader)
+		if err != nil {
+			return err
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		defer func() {
+			writer.Flush()
+			if err == nil {
+				err = writer.Error()
+			}
+		}()
+		writeRow = func(row json2csv.KeyValue) error {
+			return writer.WriteRows([]json2csv.KeyValue{row}, header)
+		}
+	}
+	write := writeRow
+	var external *json2csv.ExternalSorter
+	if sorter != nil {
+		external = json2csv.NewExternalSorter(sorter, c.Int("sort-chunk-size"))
+		defer external.Close()
+		write = external.Add
+	}
+	if dedup != nil {
+		defer dedup.Close()
+		if dedup.KeepLast {
+			if err := eachStreamRow(filename, c, opts, dedup.Scan); err != nil {
+				return err
+			}
+		}
+		next := write
+		write = func(row json2csv.KeyValue) error {
+			ok, err := dedup.Keep(row)
+			if err != nil || !ok {
+				return err
+			}
+			return next(row)
+		}
+	}
+	if err := eachStreamRow(filename, c, opts, write); err != nil {
+		return err
+	}
+	if external != nil {
+		if err := external.Each(writeRow); err != nil {
+			return err
+		}
+	}
+	if transposer != nil {
+		return transposer.Finish()
+	}
+	return nil
+}
+
+func eachStreamRow(filename string, c *cli.Context, opts *json2csv.FlattenOptions, fn func(row json2csv.KeyValue) error) error {
+	return eachStreamPass(filename, c, func(reader json2csv.JSONStreamReader, bar *progressBar) error {
+		converter, err := json2csv.NewConverter(streamOptions(c, opts, bar))
+		if err != nil {
+			return err
+		}
+		return converter.EachRow(context.Background(), reader, fn)
+	})
+}
+
+// eachStreamPass calls fn with the reader of the file and the progress bar
+// of a pass over it.
+func eachStreamPass(filename string, c *cli.Context, fn func(reader json2csv.JSONStreamReader, bar *progressBar) error) error {
+	reader, err := streamReaderFromFile(filename)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	bar := newProgressBar(c, reader, filename)
+	defer bar.Done()
+	return fn(reader, bar)
+}
+
+// streamOptions returns the options of the stream conversions, which report
+// the progress to the bar.
+func streamOptions(c *cli.Context, opts *json2csv.FlattenOptions, bar *progressBar) json2csv.Options {
+	return json2csv.Options{
+		Path:     c.String("path"),
+		Flatten:  opts,
+		Workers:  workers(c),
+		Progress: bar.Func(),
+	}
+}
+
+// workers returns the number of workers of --workers.
+func workers(c *cli.Context) int {
+	if c.Int("workers") == 0 {
+		return runtime.NumCPU()
+	}
+	return c.Int("workers")
+}
+
+func streamArrow(out *output, filename string, c *cli.Context, headerStyle json2csv.KeyFormatter, format json2csv.ArrowFormat, opts *json2csv.FlattenOptions, renamer *json2csv.Renamer) error {
+	var types json2csv.ColumnTypes
+	err := eachStreamPass(filename, c, func(reader json2csv.JSONStreamReader, bar *progressBar) error {
+		converter, err := json2csv.NewConverter(streamOptions(c, opts, bar))
+		if err != nil {
+			return err
+		}
+		types, err = converter.ColumnTypes(context.Background(), reader)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return eachStreamPass(filename, c, func(reader json2csv.JSONStreamReader, bar *progressBar) error {
+		o := streamOptions(c, opts, bar)
+		o.KeyFormatter = headerStyle
+		o.Renamer = renamer
+		converter, err := json2csv.NewConverter(o)
+		if err != nil {
+			return err
+		}
+		return converter.ConvertArrowStream(context.Background(), reader, types, out, format, c.Int("batch-size"))
+	})
+}
+
+func flattenOptions(c *cli.Context) (*json2csv.FlattenOptions, error) {
+	opts := json2csv.NewFlattenOptions()
+	opts.SliceLen = c.Int("slice-len")
+	opts.MaxDepth = c.Int("max-depth")
+	for _, v := range c.StringSlice("max-depth-at") {
+		i := strings.LastIndex(v, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid --max-depth-at value %q", v)
+		}
+		depth, err := strconv.Atoi(v[i+1:])
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf("Invalid --max-depth-at value %q", v)
+		}
+		if opts.MaxDepthOverrides == nil {
+			opts.MaxDepthOverrides = map[string]int{}
+		}
+		opts.MaxDepthOverrides[v[:i]] = depth
+	}
+
+	policy, ok := arrayPolicyTable[c.String("array-policy")]
+	if !ok {
+		return nil, fmt.Errorf("Invalid --array-policy value %q", c.String("array-policy"))
+	}
+	opts.ArrayPolicy = policy
+	opts.ArraySeparator = c.String("array-separator")
+	for _, v := range c.StringSlice("array-policy-at") {
+		i := strings.LastIndex(v, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid --array-policy-at value %q", v)
+		}
+		policy, ok := arrayPolicyTable[v[i+1:]]
+		if !ok {
+			return nil, fmt.Errorf("Invalid --array-policy-at value %q", v)
+		}
+		if opts.ArrayPolicyOverrides == nil {
+			opts.ArrayPolicyOverrides = map[string]json2csv.ArrayPolicy{}
+		}
+		opts.ArrayPolicyOverrides[v[:i]] = policy
+	}
+
+	if c.String("where") != "" {
+		filter, err := json2csv.NewExprFilter(c.String("where"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --where expression: %w", err)
+		}
+		opts.Filter = filter
+	}
+	for _, v := range c.StringSlice("add-column") {
+		column, err := json2csv.ParseComputedColumn(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --add-column value %q: %w", v, err)
+		}
+		opts.Columns = append(opts.Columns, column)
+	}
+	if c.String("melt") != "" {
+		melt, err := json2csv.NewMelt(c.String("melt"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --melt value %q: %w", c.String("melt"), err)
+		}
+		melt.KeyColumn, melt.ValueColumn, err = keyValueColumns(c)
+		if err != nil {
+			return nil, err
+		}
+		opts.Melt = melt
+	}
+	return opts, nil
+}
+
+// keyValueColumns returns --key-column and --value-column.
+func keyValueColumns(c *cli.Context) (string, string, error) {
+	for _, name := range []string{"key-column", "value-column"} {
+		if _, err := jsonpointer.New(c.String(name)); err != nil || c.String(name) == "" {
+			return "", "", fmt.Errorf("Invalid --%s value %q", name, c.String(name))
+		}
+	}
+	return c.String("key-column"), c.String("value-column"), nil
+}
+
+// pivot returns the Pivot of --pivot, or nil if it is not specified.
+func pivot(c *cli.Context) (*json2csv.Pivot, error) {
+	if c.String("pivot") == "" {
+		return nil, nil
+	}
+	p, err := json2csv.NewPivot(c.String("pivot"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --pivot value %q: %w", c.String("pivot"), err)
+	}
+	p.KeyColumn, p.ValueColumn, err = keyValueColumns(c)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// deduplicator returns the Deduplicator of --unique-by or --unique,
+// or nil if neither is specified.
+func deduplicator(c *cli.Context) (*json2csv.Deduplicator, error) {
+	keepLast, ok := keepTable[c.String("keep")]
+	if !ok {
+		return nil, fmt.Errorf("Invalid --keep value %q", c.String("keep"))
+	}
+	var keys []string
+	if c.String("unique-by") != "" {
+		var err error
+		keys, err = json2csv.ParseUniqueKeys(c.String("unique-by"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --unique-by value %q: %w", c.String("unique-by"), err)
+		}
+	} else if !c.Bool("unique") {
+		return nil, nil
+	}
+	dedup := json2csv.NewDeduplicator(keys, keepLast)
+	dedup.MaxMemoryKeys = c.Int("unique-memory-keys")
+	return dedup, nil
+}
+
+// rowSorter returns the RowSorter of --sort-by, or nil if it is not specified.
+func rowSorter(c *cli.Context) (*json2csv.RowSorter, error) {
+	if c.String("sort-by") == "" {
+		return nil, nil
+	}
+	sorter, err := json2csv.NewRowSorter(c.String("sort-by"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --sort-by value %q: %w", c.String("sort-by"), err)
+	}
+	sorter.NullsLast = c.Bool("nulls-last")
+	return sorter, nil
+}
+
+// keyFormatter returns the KeyFormatter of the --header-style value.
+// A value containing "{{" is a template.
+func keyFormatter(value string) (json2csv.KeyFormatter, error) {
+	if strings.Contains(value, "{{") {
+		f, err := json2csv.NewTemplateKeyFormatter(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --header-style template %q: %w", value, err)
+		}
+		return f, nil
+	}
+	style, ok := headerStyleTable[value]
+	if !ok {
+		return nil, fmt.Errorf("Invalid --header-style value %q", value)
+	}
+	return style, nil
+}
+
+// loadRenamer reads the rename mapping file. It returns nil if filename is empty.
+func loadRenamer(filename string) (*json2csv.Renamer, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	renamer, err := json2csv.ParseRenamer(f)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --rename file %q: %w", filename, err)
+	}
+	return renamer, nil
 }
 
 func readJSONFile(filename string) (interface{}, error) {
@@ -207,12 +864,47 @@ func readJSON(r io.Reader) (interface{}, error) {
 	return data, nil
 }
 
-func printCSV(w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, transpose bool) error {
-	csv := json2csv.NewCSVWriter(w, headerStyle, transpose)
-	csv.HeaderStyle = headerStyle
+func valueFormatter(c *cli.Context) json2csv.ValueFormatter {
+	f := json2csv.NewNumberFormat()
+	f.Fixed = c.Int("decimals") >= 0
+	f.Decimals = c.Int("decimals")
+	f.NoExponent = c.Bool("no-exponent")
+	f.BoolStyle = boolStyleTable[c.String("bool-style")]
+	f.DecimalSeparator = c.String("decimal-separator")
+	return f
+}
+
+func printCSV(w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyFormatter, transpose bool, formatter json2csv.ValueFormatter, renamer *json2csv.Renamer) error {
+	csv := json2csv.NewCSVWriter(w, json2csv.JSONPointerStyle, transpose)
+	csv.KeyFormatter = headerStyle
 	csv.Transpose = transpose
+	csv.ValueFormatter = formatter
+	csv.Renamer = renamer
 	if err := csv.WriteCSV(results); err != nil {
 		return err
 	}
 	return nil
 }
+
+func printTable(out *output, results []json2csv.KeyValue, headerStyle json2csv.KeyFormatter, transpose bool, maxColumnWidth int, formatter json2csv.ValueFormatter, renamer *json2csv.Renamer) error {
+	table := json2csv.NewTableWriter(out, json2csv.JSONPointerStyle, transpose)
+	table.KeyFormatter = headerStyle
+	table.ValueFormatter = formatter
+	table.Renamer = renamer
+	table.Width = out.width()
+	table.MaxColumnWidth = maxColumnWidth
+	return table.WriteTable(results)
+}
+
+// terminalWidth returns the width of the terminal, or 0 if unknown.
+func terminalWidth(f *os.File) int {
+	if term.IsTerminal(int(f.Fd())) {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil {
+		return width
+	}
+	return 0
+}
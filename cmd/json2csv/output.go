@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+
+	"github.com/urfave/cli"
+)
+
+// outputFlags are the flags of openOutput.
+var outputFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "output, o",
+		Usage: "write to `FILE` instead of stdout (compressed if it ends with .gz)",
+	},
+	cli.BoolFlag{
+		Name:  "force",
+		Usage: "overwrite the existing files of --output and splitting",
+	},
+}
+
+// output is the destination of the results, which is stdout or the file
+// of --output.
+type output struct {
+	io.Writer
+	file *json2csv.OutputFile
+}
+
+// openOutput opens the output. The file of --output appears when the output
+// is closed without errors.
+func openOutput(c *cli.Context) (*output, error) {
+	if c.String("output") == "" || c.String("output") == "-" {
+		return &output{Writer: os.Stdout}, nil
+	}
+	f, err := json2csv.CreateOutputFile(c.String("output"), c.Bool("force"))
+	if err != nil {
+		return nil, err
+	}
+	return &output{Writer: f, file: f}, nil
+}
+
+// checkSeekable returns an error if the output of --output-format=arrow-file
+// is not seekable, such as a pipe or a .gz file.
+func checkSeekable(c *cli.Context) error {
+	if c.String("output-format") != "arrow-file" {
+		return nil
+	}
+	if strings.HasSuffix(c.String("output"), ".gz") {
+		return fmt.Errorf("--output-format=arrow-file cannot be written to a .gz file")
+	}
+	if c.String("output") == "" || c.String("output") == "-" {
+		if _, err := os.Stdout.Seek(0, io.SeekCurrent); err != nil {
+			return fmt.Errorf("--output-format=arrow-file requires a seekable output, not a pipe")
+		}
+	}
+	return nil
+}
+
+// Seek seeks the output, which fails unless it is a file without gzip.
+func (o *output) Seek(offset int64, whence int) (int64, error) {
+	s, ok := o.Writer.(io.Seeker)
+	if !ok {
+		return 0, errors.New("Output is not seekable")
+	}
+	return s.Seek(offset, whence)
+}
+
+// Close commits the file if err is nil, otherwise discards it.
+// It returns err or the error of the commit.
+func (o *output) Close(err error) error {
+	if o.file == nil {
+		return err
+	}
+	if err != nil {
+		o.file.Close()
+		return err
+	}
+	return o.file.Commit()
+}
+
+// width returns the width of the terminal, or 0 if the output is a file.
+func (o *output) width() int {
+	if o.file != nil {
+		return 0
+	}
+	return terminalWidth(os.Stdout)
+}
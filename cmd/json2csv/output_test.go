@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+)
+
+// buildCommand builds json2csv into a temporary directory.
+func buildCommand(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "json2csv")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build: %v\n%s", err, out)
+	}
+	return bin
+}
+
+var testArrowFileOutputCases = []struct {
+	stream bool
+	output string
+	ok     bool
+}{
+	{false, "out.arrow", true},
+	{true, "out.arrow", true},
+	{false, "out.arrow.gz", false},
+	{true, "out.arrow.gz", false},
+}
+
+func TestArrowFileOutput(t *testing.T) {
+	bin := buildCommand(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.json")
+	if err := os.WriteFile(input, []byte(`[{"id": 1, "name": "foo"}, {"id": 2}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	streamInput := filepath.Join(dir, "in.jsonl")
+	if err := os.WriteFile(streamInput, []byte(`{"id": 1, "name": "foo"}`+"\n"+`{"id": 2}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for caseIndex, testCase := range testArrowFileOutputCases {
+		output := filepath.Join(t.TempDir(), testCase.output)
+		args := []string{"--output-format=arrow-file", "-o", output, input}
+		if testCase.stream {
+			args = []string{"--output-format=arrow-file", "--stream", "-o", output, streamInput}
+		}
+		out, err := exec.Command(bin, args...).CombinedOutput()
+		if ok := err == nil; ok != testCase.ok {
+			t.Errorf("%d: Expected %v, but %v: %s", caseIndex, testCase.ok, ok, out)
+			continue
+		}
+		if !testCase.ok {
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Errorf("%d: Expected no file, but %v", caseIndex, err)
+			}
+			continue
+		}
+
+		f, err := os.Open(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := ipc.NewFileReader(f)
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			f.Close()
+			continue
+		}
+		if n := r.Schema().NumFields(); n != 2 {
+			t.Errorf("%d: Expected 2 fields, but %d", caseIndex, n)
+		}
+		rows := int64(0)
+		for i := 0; i < r.NumRecords(); i++ {
+			rec, err := r.Record(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows += rec.NumRows()
+		}
+		if rows != 2 {
+			t.Errorf("%d: Expected 2 rows, but %d", caseIndex, rows)
+		}
+		r.Close()
+		f.Close()
+	}
+}
+
+var testOutputOnErrorCases = []struct {
+	args []string
+}{
+	{[]string{"--stream", "missing.jsonl"}},
+	{[]string{"--stream", "missing.zip"}},
+	{[]string{"--stream", "--output-format=arrow-file", "missing.jsonl"}},
+	{[]string{"missing.json"}},
+}
+
+func TestOutputOnError(t *testing.T) {
+	bin := buildCommand(t)
+	dir := t.TempDir()
+
+	for caseIndex, testCase := range testOutputOnErrorCases {
+		outDir := t.TempDir()
+		args := append([]string{"-o", filepath.Join(outDir, "res.csv")}, testCase.args...)
+		cmd := exec.Command(bin, args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err == nil {
+			t.Errorf("%d: Expected an error, but nil: %s", caseIndex, out)
+			continue
+		}
+		entries, err := os.ReadDir(outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("%d: Expected no files, but %v", caseIndex, entries)
+		}
+	}
+}
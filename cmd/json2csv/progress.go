@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yukithm/json2csv"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	progressBarWidth    = 30
+	progressBarInterval = 100 * time.Millisecond
+)
+
+// progressBar prints the progress of a pass over the input of --progress.
+type progressBar struct {
+	w     io.Writer
+	total int64 // the size of the input, or 0 if unknown
+	last  time.Time
+	shown bool
+}
+
+// newProgressBar returns the progress bar of the reader of the file, or nil
+// without --progress. The total is the size of the file, or the compressed
+// size of the files in a zip file, which doesn't count its headers.
+func newProgressBar(c *cli.Context, reader json2csv.JSONStreamReader, filename string) *progressBar {
+	if !c.Bool("progress") {
+		return nil
+	}
+	b := &progressBar{w: os.Stderr}
+	if zipReader, ok := reader.(*json2csv.JSONStreamZipReader); ok {
+		b.total = zipReader.BytesTotal()
+	} else if info, err := os.Stat(filename); err == nil {
+		b.total = info.Size()
+	}
+	return b
+}
+
+// Func returns the ProgressFunc of the bar, or nil if the bar is nil.
+func (b *progressBar) Func() json2csv.ProgressFunc {
+	if b == nil {
+		return nil
+	}
+	return b.update
+}
+
+// Done ends the line of the bar.
+func (b *progressBar) Done() {
+	if b != nil && b.shown {
+		fmt.Fprintln(b.w)
+	}
+}
+
+func (b *progressBar) update(p json2csv.Progress) {
+	now := time.Now()
+	if b.shown && now.Sub(b.last) < progressBarInterval && (b.total == 0 || p.Bytes < b.total) {
+		return
+	}
+	b.last = now
+	b.shown = true
+
+	var s strings.Builder
+	s.WriteString("\r")
+	if b.total > 0 {
+		ratio := float64(p.Bytes) / float64(b.total)
+		if ratio > 1 {
+			ratio = 1
+		}
+		done := int(ratio * progressBarWidth)
+		fmt.Fprintf(&s, "[%s%s] %3.0f%% %s/%s ", strings.Repeat("=", done), strings.Repeat(" ", progressBarWidth-done), ratio*100, formatBytes(p.Bytes), formatBytes(b.total))
+	}
+	fmt.Fprintf(&s, "%d records, %d rows\x1b[K", p.Records, p.Rows)
+	io.WriteString(b.w, s.String())
+}
+
+// formatBytes formats n bytes like "1.5MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1fTB", value)
+}
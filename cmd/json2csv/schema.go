@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+
+	"github.com/urfave/cli"
+)
+
+var schemaFormats = map[string]bool{
+	"json-schema": true,
+	"table":       true,
+}
+
+// inputFlags are the flags to read and flatten the input, shared by subcommands.
+var inputFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "header-style",
+		Value: "jsonpointer",
+		Usage: "header style (jsonpointer, slash, dot, dot-bracket, snake, camel) or template",
+	},
+	cli.StringFlag{
+		Name:  "path",
+		Usage: "target path (JSON Pointer) of the content",
+	},
+	cli.IntFlag{
+		Name:  "slice-len",
+		Value: math.MaxInt,
+		Usage: "Specify the length of the slice to be processed.",
+	},
+	cli.StringFlag{
+		Name:  "where",
+		Usage: "convert only records matching the expression (e.g. '/age >= 18 && exists(/email)')",
+	},
+	cli.StringSliceFlag{
+		Name:  "add-column",
+		Usage: "add a column computed from each row (e.g. 'total=/price * /qty')",
+	},
+	cli.IntFlag{
+		Name:  "max-depth",
+		Usage: "write objects and arrays deeper than this as JSON strings (0: unlimited)",
+	},
+	cli.StringSliceFlag{
+		Name:  "max-depth-at",
+		Usage: "override --max-depth under the path, relative to it (e.g. /payload=1)",
+	},
+	cli.StringFlag{
+		Name:  "array-policy",
+		Value: "index",
+		Usage: "how arrays are flattened (index, join, json, count)",
+	},
+	cli.StringSliceFlag{
+		Name:  "array-policy-at",
+		Usage: "override --array-policy for arrays at the path (e.g. /tags=join)",
+	},
+	cli.StringFlag{
+		Name:  "array-separator",
+		Value: json2csv.DefaultArraySeparator,
+		Usage: "separator of joined arrays",
+	},
+	cli.StringFlag{
+		Name:  "melt",
+		Usage: "convert columns under the path into rows of --key-column and --value-column (e.g. /metrics)",
+	},
+	cli.StringFlag{
+		Name:  "key-column",
+		Value: json2csv.DefaultKeyColumn,
+		Usage: "column of keys of --melt and --pivot",
+	},
+	cli.StringFlag{
+		Name:  "value-column",
+		Value: json2csv.DefaultValueColumn,
+		Usage: "column of values of --melt and --pivot",
+	},
+	cli.BoolFlag{
+		Name:  "stream",
+		Usage: "scan data stream",
+	},
+	cli.IntFlag{
+		Name:  "workers",
+		Value: 1,
+		Usage: "goroutines to decode and flatten records in stream mode, output order is kept (0: number of CPUs)",
+	},
+	cli.BoolFlag{
+		Name:  "progress",
+		Usage: "show progress of each pass over the input in stream mode on stderr",
+	},
+}
+
+var schemaCommand = cli.Command{
+	Name:      "schema",
+	Usage:     "infer the type of each column",
+	ArgsUsage: "[FILE]",
+	Flags: append(append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Value: "json-schema",
+			Usage: "output format (json-schema, table)",
+		},
+		cli.IntFlag{
+			Name:  "samples",
+			Value: json2csv.DefaultMaxSamples,
+			Usage: "number of sample values of each column",
+		},
+	}, inputFlags...), outputFlags...),
+	Before: func(c *cli.Context) error {
+		if err := applyProfile(c); err != nil {
+			return err
+		}
+		if _, err := keyFormatter(c.String("header-style")); err != nil {
+			return err
+		}
+		if c.Int("workers") < 0 {
+			return fmt.Errorf("Invalid --workers value %d", c.Int("workers"))
+		}
+		if !schemaFormats[c.String("format")] {
+			return fmt.Errorf("Invalid --format value %q", c.String("format"))
+		}
+		return nil
+	},
+	Action: func(c *cli.Context) error {
+		return schemaAction(c)
+	},
+}
+
+func schemaAction(c *cli.Context) error {
+	opts, err := flattenOptions(c)
+	if err != nil {
+		return err
+	}
+	builder := json2csv.NewSchemaBuilder()
+	builder.MaxSamples = c.Int("samples")
+	if c.NArg() > 0 && c.Args()[0] != "-" && c.Bool("stream") {
+		err := eachStreamRow(c.Args()[0], c, opts, func(row json2csv.KeyValue) error {
+			builder.Update([]json2csv.KeyValue{row})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		var data interface{}
+		if c.NArg() > 0 && c.Args()[0] != "-" {
+			data, err = readJSONFile(c.Args()[0])
+		} else {
+			data, err = readJSON(os.Stdin)
+		}
+		if err != nil {
+			return err
+		}
+		if c.String("path") != "" {
+			data, err = jsonpointer.Get(data, c.String("path"))
+			if err != nil {
+				return err
+			}
+		}
+		results, err := json2csv.JSON2CSVWithOptions(data, nil, opts)
+		if err != nil {
+			return err
+		}
+		builder.Update(results)
+	}
+
+	schema, err := builder.Schema()
+	if err != nil {
+		return err
+	}
+	headerStyle, err := keyFormatter(c.String("header-style"))
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(c)
+	if err != nil {
+		return err
+	}
+	if c.String("format") == "table" {
+		return out.Close(printSchemaTable(out, schema, headerStyle))
+	}
+	b, err := schema.JSONSchema(headerStyle)
+	if err == nil {
+		_, err = fmt.Fprintln(out, string(b))
+	}
+	return out.Close(err)
+}
+
+func printSchemaTable(w io.Writer, schema *json2csv.Schema, headerStyle json2csv.KeyFormatter) error {
+	names, err := schema.Names(headerStyle)
+	if err != nil {
+		return err
+	}
+	header := []string{"column", "type", "nullable", "min_length", "max_length", "samples"}
+	records := make([][]string, 0, len(schema.Columns))
+	for i, col := range schema.Columns {
+		records = append(records, []string{
+			names[i],
+			col.Type.String(),
+			strconv.FormatBool(col.Nullable),
+			strconv.Itoa(col.MinLength),
+			strconv.Itoa(col.MaxLength),
+			strings.Join(col.Samples, ", "),
+		})
+	}
+	table := json2csv.NewTableWriter(w, json2csv.JSONPointerStyle, false)
+	table.MaxColumnWidth = 40
+	return table.WriteRecords(header, records)
+}
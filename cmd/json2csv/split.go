@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+
+	"github.com/yukithm/json2csv"
+
+	"github.com/urfave/cli"
+)
+
+// splitting reports whether the output is split into files.
+func splitting(c *cli.Context) bool {
+	return c.String("split-by") != "" || c.Int("max-rows") > 0
+}
+
+// partitionWriter returns PartitionWriter which writes PREFIX-NAME.csv files,
+// and the function to discard the files not closed yet on errors.
+// Each file appears when the writer closes it, like --output.
+func partitionWriter(c *cli.Context, headerStyle json2csv.KeyFormatter, renamer *json2csv.Renamer, header func(name, value string) json2csv.CSVHeader) (*json2csv.PartitionWriter, func()) {
+	p := json2csv.NewPartitioner(c.String("split-by"), c.Int("max-rows"))
+	var files []*json2csv.OutputFile
+	open := func(name string) (*json2csv.CSVWriter, io.Closer, error) {
+		f, err := json2csv.CreateOutputFile(c.String("split-prefix")+"-"+name+".csv", c.Bool("force"))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+		return csvWriter(f, c, headerStyle, renamer), partitionFile{f}, nil
+	}
+	discard := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	return json2csv.NewPartitionWriter(p, open, header), discard
+}
+
+// partitionFile commits the file when PartitionWriter closes it.
+type partitionFile struct {
+	*json2csv.OutputFile
+}
+
+func (f partitionFile) Close() error {
+	return f.Commit()
+}
+
+// writePartitions writes the rows into files.
+// Each file has its own header unless --shared-header.
+func writePartitions(c *cli.Context, results []json2csv.KeyValue, headerStyle json2csv.KeyFormatter, renamer *json2csv.Renamer) error {
+	each := func(fn func(row json2csv.KeyValue) error) error {
+		for _, result := range results {
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var header func(name, value string) json2csv.CSVHeader
+	if c.Bool("shared-header") {
+		csvHeader := json2csv.CSVHeader{}
+		for _, result := range results {
+			for key := range result {
+				csvHeader[key] = ""
+			}
+		}
+		header = func(name, value string) json2csv.CSVHeader { return csvHeader }
+	} else {
+		headers, err := json2csv.PartitionHeaders(json2csv.NewPartitioner(c.String("split-by"), c.Int("max-rows")), each)
+		if err != nil {
+			return err
+		}
+		header = func(name, value string) json2csv.CSVHeader { return headers[name] }
+	}
+
+	w, discard := partitionWriter(c, headerStyle, renamer, header)
+	if err := each(w.Write); err != nil {
+		discard()
+		return err
+	}
+	return w.Close()
+}
+
+// csvWriter returns CSVWriter with the output options.
+func csvWriter(w io.Writer, c *cli.Context, headerStyle json2csv.KeyFormatter, renamer *json2csv.Renamer) *json2csv.CSVWriter {
+	writer := json2csv.NewCSVWriter(w, json2csv.JSONPointerStyle, false)
+	writer.KeyFormatter = headerStyle
+	writer.ValueFormatter = valueFormatter(c)
+	writer.Renamer = renamer
+	return writer
+}
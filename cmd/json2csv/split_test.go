@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var testSplitOutputCases = []struct {
+	args     []string
+	ok       bool
+	expected map[string]string
+}{
+	{
+		[]string{"--split-by=/region", "in.json"},
+		false,
+		map[string]string{"out-us.csv": "old\n"},
+	},
+	{
+		[]string{"--split-by=/region", "--force", "in.json"},
+		true,
+		map[string]string{"out-us.csv": "/id,/region\n1,us\n", "out-eu.csv": "/id,/region\n2,eu\n"},
+	},
+	{
+		[]string{"--split-by=/region", "--stream", "in.jsonl"},
+		false,
+		map[string]string{"out-us.csv": "old\n"},
+	},
+	{
+		[]string{"--split-by=/region", "--stream", "--force", "in.jsonl"},
+		true,
+		map[string]string{"out-us.csv": "/id,/region\n1,us\n", "out-eu.csv": "/id,/region\n2,eu\n"},
+	},
+}
+
+func TestSplitOutput(t *testing.T) {
+	bin := buildCommand(t)
+	for caseIndex, testCase := range testSplitOutputCases {
+		dir := t.TempDir()
+		files := map[string]string{
+			"in.json":    `[{"id": 1, "region": "us"}, {"id": 2, "region": "eu"}]`,
+			"in.jsonl":   `{"id": 1, "region": "us"}` + "\n" + `{"id": 2, "region": "eu"}` + "\n",
+			"out-us.csv": "old\n",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(bin, testCase.args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if ok := err == nil; ok != testCase.ok {
+			t.Errorf("%d: Expected %v, but %v: %s", caseIndex, testCase.ok, ok, out)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(dir, "*.csv*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := map[string]string{}
+		for _, path := range matches {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual[filepath.Base(path)] = string(b)
+		}
+		if len(actual) != len(testCase.expected) {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, actual)
+			continue
+		}
+		for name, content := range testCase.expected {
+			if actual[name] != content {
+				t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, actual)
+				break
+			}
+		}
+	}
+}
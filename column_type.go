@@ -0,0 +1,99 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// ColumnType represents the type of values in a column.
+type ColumnType uint
+
+// Column types
+const (
+	// no values, or only nulls
+	NullColumn ColumnType = iota
+
+	// integer numbers
+	IntegerColumn
+
+	// numbers which contain floating point numbers
+	FloatColumn
+
+	// true/false
+	BoolColumn
+
+	// strings
+	StringColumn
+
+	// values of different types
+	MixedColumn
+)
+
+var columnTypeNames = map[ColumnType]string{
+	NullColumn:    "null",
+	IntegerColumn: "integer",
+	FloatColumn:   "float",
+	BoolColumn:    "bool",
+	StringColumn:  "string",
+	MixedColumn:   "mixed",
+}
+
+func (t ColumnType) String() string {
+	if name, ok := columnTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Merge returns the type which can hold both t and other.
+func (t ColumnType) Merge(other ColumnType) ColumnType {
+	switch {
+	case t == other:
+		return t
+	case t == NullColumn:
+		return other
+	case other == NullColumn:
+		return t
+	case (t == IntegerColumn && other == FloatColumn) || (t == FloatColumn && other == IntegerColumn):
+		return FloatColumn
+	default:
+		return MixedColumn
+	}
+}
+
+// TypeOfValue returns the ColumnType of a flattened value.
+func TypeOfValue(value interface{}) ColumnType {
+	switch v := value.(type) {
+	case nil:
+		return NullColumn
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return IntegerColumn
+		}
+		return FloatColumn
+	case int64, uint64:
+		return IntegerColumn
+	case float64:
+		return FloatColumn
+	case bool:
+		return BoolColumn
+	case string:
+		return StringColumn
+	default:
+		return MixedColumn
+	}
+}
+
+// ColumnTypes represents key(path)/type map.
+type ColumnTypes map[string]ColumnType
+
+// Update merges types of values in the result.
+func (ct ColumnTypes) Update(result KeyValue) {
+	for key, value := range result {
+		if t, ok := ct[key]; ok {
+			ct[key] = t.Merge(TypeOfValue(value))
+		} else {
+			ct[key] = TypeOfValue(value)
+		}
+	}
+}
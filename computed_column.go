@@ -0,0 +1,60 @@
+package json2csv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukithm/json2csv/expr"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// ComputedColumn is a column computed from each flattened row.
+type ComputedColumn struct {
+	// Key is the JSON Pointer of the column.
+	Key  string
+	Expr *expr.Expr
+}
+
+// NewComputedColumn returns new ComputedColumn.
+// name is a JSON Pointer like "/total", or a plain name like "total".
+// Paths in the expression are keys of flattened rows like "/items/0/price".
+func NewComputedColumn(name, expression string) (*ComputedColumn, error) {
+	key := name
+	if !strings.HasPrefix(name, "/") {
+		key = jsonpointer.JSONPointer{jsonpointer.Token(name)}.String()
+	}
+	if _, err := jsonpointer.New(key); err != nil || key == "" {
+		return nil, fmt.Errorf("Invalid column name %q", name)
+	}
+	e, err := expr.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &ComputedColumn{Key: key, Expr: e}, nil
+}
+
+// ParseComputedColumn parses "name=expression" and returns new ComputedColumn.
+func ParseComputedColumn(s string) (*ComputedColumn, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return nil, fmt.Errorf("Invalid column definition %q", s)
+	}
+	return NewComputedColumn(strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]))
+}
+
+// Apply evaluates the expression and sets the value to the row.
+// If the value is null, the column is left empty. It is an error if the row
+// already has the column.
+func (c *ComputedColumn) Apply(row KeyValue) error {
+	if _, ok := row[c.Key]; ok {
+		return fmt.Errorf("Column %s already exists", c.Key)
+	}
+	v, err := c.Expr.Eval(row)
+	if err != nil {
+		return fmt.Errorf("Failed to compute %s: %w", c.Key, err)
+	}
+	if v != nil {
+		row[c.Key] = v
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package json2csv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Options configures a Converter. The zero value converts like JSON2CSV
+// with JSONPointerStyle header and the default CSV dialect.
+type Options struct {
+	// Path is a JSON Pointer to the data to convert in the document, or in
+	// each record of streams.
+	Path string
+
+	// Flatten configures flattening. If nil, NewFlattenOptions is used.
+	Flatten *FlattenOptions
+
+	// KeyStyle is the style of header keys.
+	KeyStyle KeyStyle
+
+	// KeyFormatter formats header keys instead of KeyStyle if not nil.
+	KeyFormatter KeyFormatter
+
+	// Renamer renames header keys after they are formatted if not nil.
+	Renamer *Renamer
+
+	// Transpose writes a row for each key and a column for each row.
+	Transpose bool
+
+	// ValueFormatter formats each value. If nil, values are written as is.
+	ValueFormatter ValueFormatter
+
+	// NullValue is written for null and missing values.
+	NullValue string
+
+	// Comma is the field delimiter. If 0, ',' is used.
+	Comma rune
+
+	// UseCRLF terminates lines with \r\n instead of \n.
+	UseCRLF bool
+
+	// Workers is the number of goroutines to decode and flatten records of
+	// streams. Values less than 2 mean serial processing.
+	// Filter of Flatten and ValueFormatter must be safe for concurrent use
+	// with more workers.
+	Workers int
+
+	// Progress is called with the progress of streams if not nil.
+	Progress ProgressFunc
+
+	// Sort sorts the rows of Flatten, Convert and the conversions of streams
+	// if not nil. Rows of streams are sorted in chunks of SortChunkSize rows,
+	// which are merged through temporary files.
+	Sort *RowSorter
+
+	// SortChunkSize is the number of rows sorted in memory. If 0,
+	// DefaultSortChunkSize is used.
+	SortChunkSize int
+}
+
+// Converter converts JSON into CSV with Options.
+type Converter struct {
+	opts Options
+	f    *flattener // nil for the stream functions
+}
+
+// NewConverter returns new Converter of the options, which are validated.
+func NewConverter(opts Options) (*Converter, error) {
+	if opts.Flatten == nil {
+		opts.Flatten = NewFlattenOptions()
+	}
+	if _, err := jsonpointer.New(opts.Path); err != nil {
+		return nil, err
+	}
+	f, err := newFlattener(opts.Flatten)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Comma != 0 && !validComma(opts.Comma) {
+		return nil, fmt.Errorf("Invalid comma %q", opts.Comma)
+	}
+	return &Converter{opts: opts, f: f}, nil
+}
+
+// streamConverter returns the converter of the stream functions, which
+// report invalid options on conversion as before.
+func streamConverter(path string, opts *FlattenOptions) *Converter {
+	return &Converter{opts: Options{Path: path, Flatten: opts}}
+}
+
+// flattener returns the flattener created by NewConverter, or new one for
+// each conversion of the stream functions.
+func (c *Converter) flattener() (*flattener, error) {
+	if c.f != nil {
+		return c.f, nil
+	}
+	return newFlattener(c.opts.Flatten)
+}
+
+func validComma(r rune) bool {
+	return r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// Options returns the options of the converter.
+func (c *Converter) Options() Options {
+	return c.opts
+}
+
+// NewWriter returns new CSVWriter which writes to w with the options.
+func (c *Converter) NewWriter(w io.Writer) *CSVWriter {
+	writer := NewCSVWriter(w, c.opts.KeyStyle, c.opts.Transpose)
+	writer.KeyFormatter = c.opts.KeyFormatter
+	writer.Renamer = c.opts.Renamer
+	writer.ValueFormatter = c.opts.ValueFormatter
+	writer.NullValue = c.opts.NullValue
+	if c.opts.Comma != 0 {
+		writer.Comma = c.opts.Comma
+	}
+	writer.UseCRLF = c.opts.UseCRLF
+	return writer
+}
+
+// Flatten flattens the data at Path into rows.
+func (c *Converter) Flatten(data interface{}) ([]KeyValue, error) {
+	f, err := c.flattener()
+	if err != nil {
+		return nil, err
+	}
+	results, err := flattenRecord(data, c.opts.Path, f)
+	if err == nil && c.opts.Sort != nil {
+		c.opts.Sort.Sort(results)
+	}
+	return results, err
+}
+
+// Convert converts the decoded JSON data and writes CSV to w.
+func (c *Converter) Convert(w io.Writer, data interface{}) error {
+	results, err := c.Flatten(data)
+	if err != nil {
+		return err
+	}
+	return c.NewWriter(w).WriteCSV(results)
+}
+
+// Header scans the stream and returns the header of all rows, to write the
+// rows of the stream read again by ConvertStream. Rows of the progress are
+// always 0.
+func (c *Converter) Header(ctx context.Context, reader JSONStreamReader) (CSVHeader, error) {
+	header := CSVHeader{}
+	err := c.eachRow(ctx, reader, c.scanProgress(), func(row KeyValue) error {
+		for key := range row {
+			header[key] = ""
+		}
+		return nil
+	})
+	return header, err
+}
+
+// ColumnTypes scans the stream and returns the type of each column, to
+// write the rows of the stream read again by ConvertArrowStream. Rows of the
+// progress are always 0.
+func (c *Converter) ColumnTypes(ctx context.Context, reader JSONStreamReader) (ColumnTypes, error) {
+	types := ColumnTypes{}
+	err := c.eachRow(ctx, reader, c.scanProgress(), func(row KeyValue) error {
+		types.Update(row)
+		return nil
+	})
+	return types, err
+}
+
+// scanProgress returns Progress of the options without the rows, for the
+// passes which write nothing.
+func (c *Converter) scanProgress() ProgressFunc {
+	if c.opts.Progress == nil {
+		return nil
+	}
+	return func(p Progress) {
+		p.Rows = 0
+		c.opts.Progress(p)
+	}
+}
+
+// ConvertStream writes the rows of the stream in CSV with the header.
+// It stops with the error of ctx when it is done, and rows written before
+// that are flushed. With Transpose, the rows are written by Transposer, so
+// nothing is written when it stops.
+func (c *Converter) ConvertStream(ctx context.Context, reader JSONStreamReader, csvHeader CSVHeader, w io.Writer) error {
+	return c.writeStream(ctx, reader, csvHeader, c.NewWriter(w))
+}
+
+// ConvertArrowStream writes the rows of the stream in Apache Arrow IPC of
+// the format with the column types, writing a record batch for every
+// batchSize rows. It stops with the error of ctx when it is done.
+func (c *Converter) ConvertArrowStream(ctx context.Context, reader JSONStreamReader, types ColumnTypes, w io.Writer, format ArrowFormat, batchSize int) error {
+	writer := NewArrowWriter(w, c.opts.KeyStyle, format)
+	writer.KeyFormatter = c.opts.KeyFormatter
+	writer.Renamer = c.opts.Renamer
+	if err := writer.WriteSchema(types); err != nil {
+		return err
+	}
+	batch := make([]KeyValue, 0, batchSize)
+	err := c.eachSortedRow(ctx, reader, func(row KeyValue) error {
+		batch = append(batch, row)
+		if len(batch) < batchSize {
+			return nil
+		}
+		err := writer.WriteRecordBatch(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		if err := writer.WriteRecordBatch(batch); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// EachRow calls fn for each row of the stream in the order of the stream,
+// regardless of Sort. It stops with the error of ctx when it is done.
+func (c *Converter) EachRow(ctx context.Context, reader JSONStreamReader, fn func(row KeyValue) error) error {
+	return c.eachRow(ctx, reader, c.opts.Progress, fn)
+}
+
+// eachSortedRow is like EachRow, but calls fn in the order of Sort if set.
+func (c *Converter) eachSortedRow(ctx context.Context, reader JSONStreamReader, fn func(row KeyValue) error) error {
+	if c.opts.Sort == nil {
+		return c.EachRow(ctx, reader, fn)
+	}
+	chunkSize := c.opts.SortChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultSortChunkSize
+	}
+	sorter := NewExternalSorter(c.opts.Sort, chunkSize)
+	defer sorter.Close()
+	if err := c.EachRow(ctx, reader, sorter.Add); err != nil {
+		return err
+	}
+	return sorter.Each(fn)
+}
+
+func (c *Converter) eachRow(ctx context.Context, reader JSONStreamReader, progress ProgressFunc, fn func(row KeyValue) error) error {
+	f, err := c.flattener()
+	if err != nil {
+		return err
+	}
+	p := newProgress(ctx, progress)
+	if c.opts.Workers > 1 {
+		return eachParallel(reader, c.opts.Path, f, c.opts.Workers, p, nil, func(rows []KeyValue, records [][]string) error {
+			for _, row := range rows {
+				if err := fn(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	for reader.HasNext() {
+		rows, err := p.readRows(reader, c.opts.Path, f)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		p.wrote(len(rows))
+	}
+	return nil
+}
+
+// writeStream writes the rows of the stream with the writer. If the writer
+// transposes, the rows are written by Transposer.
+func (c *Converter) writeStream(ctx context.Context, reader JSONStreamReader, csvHeader CSVHeader, writer *CSVWriter) error {
+	f, err := c.flattener()
+	if err != nil {
+		return err
+	}
+	p := newProgress(ctx, c.opts.Progress)
+	header, err := writer.CompileHeader(csvHeader)
+	if err != nil {
+		return err
+	}
+	write := writer.Write
+	var transposer *Transposer
+	if writer.Transpose {
+		transposer = writer.NewTransposer(header)
+		defer transposer.Close()
+		write = transposer.writeRecord
+	} else {
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		defer writer.Flush()
+	}
+
+	if c.opts.Sort != nil {
+		err = c.eachSortedRow(ctx, reader, func(row KeyValue) error {
+			return write(writer.record(row, header))
+		})
+		if err != nil {
+			return err
+		}
+	} else if c.opts.Workers > 1 {
+		format := func(rows []KeyValue) [][]string {
+			records := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				records = append(records, writer.record(row, header))
+			}
+			return records
+		}
+		err = eachParallel(reader, c.opts.Path, f, c.opts.Workers, p, format, func(rows []KeyValue, records [][]string) error {
+			for _, record := range records {
+				if err := write(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		for reader.HasNext() {
+			rows, err := p.readRows(reader, c.opts.Path, f)
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				if err := write(writer.record(row, header)); err != nil {
+					return err
+				}
+			}
+			p.wrote(len(rows))
+		}
+	}
+
+	if transposer != nil {
+		return transposer.Finish()
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,177 @@
+package json2csv
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+)
+
+var testConverterCases = []struct {
+	json     string
+	opts     Options
+	expected string
+}{
+	{
+		`[{"a": 1, "b": {"c": "x"}}, {"a": 2, "d": null}]`,
+		Options{},
+		"/a,/b/c\n1,x\n2,\n",
+	},
+	{
+		`{"items": [{"a": 1, "b": [1, 2, 3]}, {"a": 2}]}`,
+		Options{Path: "/items", KeyStyle: DotBracketStyle, Flatten: &FlattenOptions{SliceLen: 2}},
+		"a,b[0],b[1]\n1,1,2\n2,,\n",
+	},
+	{
+		`[{"a": 1, "b": "x,y"}, {"b": true}]`,
+		Options{Comma: ';', UseCRLF: true, NullValue: "NULL"},
+		"/a;/b\r\n1;x,y\r\nNULL;true\r\n",
+	},
+	{
+		`[{"a": 1, "b": true}, {"a": 3}]`,
+		Options{Transpose: true, NullValue: "-", ValueFormatter: &NumberFormat{BoolStyle: YesNoBool}},
+		"/a,1,3\n/b,yes,-\n",
+	},
+}
+
+func TestConverter(t *testing.T) {
+	for caseIndex, testCase := range testConverterCases {
+		data, err := json2obj(testCase.json)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewConverter(testCase.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := c.Convert(&b, data); err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			continue
+		}
+		if b.String() != testCase.expected {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, b.String())
+		}
+	}
+}
+
+func TestNewConverterError(t *testing.T) {
+	for caseIndex, opts := range []Options{
+		{Path: "items"},
+		{Comma: '"'},
+		{Comma: '\n'},
+		{Flatten: &FlattenOptions{MaxDepthOverrides: map[string]int{"a": 1}}},
+	} {
+		if _, err := NewConverter(opts); err == nil {
+			t.Errorf("%d: Expected an error, but nil", caseIndex)
+		}
+	}
+}
+
+// newTestConverter returns new Converter of the options.
+func newTestConverter(t testing.TB, opts Options) *Converter {
+	t.Helper()
+	c, err := NewConverter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// convertTestLines converts the file of createTestLines by the converter.
+func convertTestLines(t *testing.T, c *Converter, filename string) string {
+	t.Helper()
+	reader := openTestLines(t, filename)
+	header, err := c.Header(context.Background(), reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	reader = openTestLines(t, filename)
+	defer reader.Close()
+	if err := c.ConvertStream(context.Background(), reader, header, &b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestConverterStream(t *testing.T) {
+	filename := createTestLines(t, 200)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+
+	reader := openTestLines(t, filename)
+	csvHeader, err := JSON2CSVHeader(reader, "", math.MaxInt)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected bytes.Buffer
+	reader = openTestLines(t, filename)
+	err = JSON2CSVOnline(reader, csvHeader, &expected, DotBracketStyle, false, "", math.MaxInt)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		c, err := NewConverter(Options{Flatten: opts, KeyStyle: DotBracketStyle, Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := openTestLines(t, filename)
+		header, err := c.Header(context.Background(), reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(header) != len(csvHeader) {
+			t.Errorf("workers=%d: Expected %d columns, but %d", workers, len(csvHeader), len(header))
+		}
+
+		var b bytes.Buffer
+		reader = openTestLines(t, filename)
+		err = c.ConvertStream(context.Background(), reader, header, &b)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected.String() {
+			t.Errorf("workers=%d: Expected the same output as JSON2CSVOnline, but differs", workers)
+		}
+	}
+}
+
+func TestConverterStreamSort(t *testing.T) {
+	filename := createTestLines(t, 200)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+	sorter, err := NewRowSorter("-/score,/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the in-memory output of the sorted rows
+	var rows []KeyValue
+	reader := openTestLines(t, filename)
+	if err := newTestConverter(t, Options{Flatten: opts}).EachRow(context.Background(), reader, func(row KeyValue) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	sorter.Sort(rows)
+	var expected bytes.Buffer
+	if err := NewCSVWriter(&expected, JSONPointerStyle, false).WriteCSV(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chunkSize := range []int{0, 7} {
+		for _, workers := range []int{1, 4} {
+			c := newTestConverter(t, Options{Flatten: opts, Workers: workers, Sort: sorter, SortChunkSize: chunkSize})
+			if actual := convertTestLines(t, c, filename); actual != expected.String() {
+				t.Errorf("chunkSize=%d workers=%d: Expected the same output as sorted in memory, but differs", chunkSize, workers)
+			}
+		}
+	}
+}
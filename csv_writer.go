@@ -24,6 +24,12 @@ const (
 
 	// "foo.bar[0].baz"
 	DotBracketStyle
+
+	// "foo_bar_0_baz"
+	SnakeCaseStyle
+
+	// "fooBar0Baz"
+	CamelCaseStyle
 )
 
 // CSVWriter writes CSV data.
@@ -31,81 +37,95 @@ type CSVWriter struct {
 	*csv.Writer
 	HeaderStyle KeyStyle
 	Transpose   bool
+
+	// ValueFormatter formats each value. If nil, values are formatted as is.
+	ValueFormatter ValueFormatter
+
+	// KeyFormatter formats header keys instead of HeaderStyle if not nil.
+	KeyFormatter KeyFormatter
+
+	// Renamer renames header keys after HeaderStyle is applied.
+	Renamer *Renamer
+
+	// NullValue is written for null and missing values.
+	NullValue string
+
+	out io.Writer // the writer of csv.Writer
 }
 
 // NewCSVWriter returns new CSVWriter with given JSONPointerStyle and transpose.
 func NewCSVWriter(w io.Writer, style KeyStyle, transpose bool) *CSVWriter {
 	return &CSVWriter{
-		csv.NewWriter(w),
-		style,
-		transpose,
+		Writer:      csv.NewWriter(w),
+		out:         w,
+		HeaderStyle: style,
+		Transpose:   transpose,
 	}
 }
 
 // WriterHeader only writes header.
 func (w *CSVWriter) WriterHeader(csvHeader CSVHeader) error {
-	header, err := w.FormatHeader(csvHeader)
+	header, err := w.CompileHeader(csvHeader)
 	if err != nil {
 		return err
 	}
+	return w.WriteHeader(header)
+}
 
-	if err := w.Write(header); err != nil {
+// FormatHeader formats the given header with CSVWriter.HeaderStyle.
+func (w *CSVWriter) FormatHeader(csvHeader CSVHeader) ([]string, error) {
+	header, err := w.CompileHeader(csvHeader)
+	if err != nil {
+		return nil, err
+	}
+	return header.Names, nil
+}
+
+// CompileHeader compiles the given header with the style and the renamer of
+// the writer, to write rows by WriteRows.
+func (w *CSVWriter) CompileHeader(csvHeader CSVHeader) (*Header, error) {
+	return NewHeader(csvHeader, keyFormatterOf(w.HeaderStyle, w.KeyFormatter), w.Renamer)
+}
+
+// WriteHeader writes the names of the compiled header.
+func (w *CSVWriter) WriteHeader(header *Header) error {
+	if err := w.Write(header.Names); err != nil {
 		return err
 	}
 	w.Flush()
-	if err := w.Error(); err != nil {
-		return err
+	return w.Error()
+}
+
+// WriteRows writes CSV rows according to the compiled header like
+// WriteCSVByHeader, but doesn't flush. Call Flush after writing.
+func (w *CSVWriter) WriteRows(results []KeyValue, header *Header) error {
+	for _, result := range results {
+		if err := w.Write(w.record(result, header)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// FormatHeader formats the given header with CSVWriter.HeaderStyle.
-func (w *CSVWriter) FormatHeader(csvHeader CSVHeader) ([]string, error) {
-	result := KeyValue{}
-	for h := range csvHeader {
-		result[h] = ""
-	}
-	results := []KeyValue{result}
-	pts, err := allPointers(results)
-	if err != nil {
-		return nil, err
-	}
-	sort.Sort(pts)
-	header := w.getHeader(pts)
-	return header, nil
+// record returns the values of the row in the order of the header.
+func (w *CSVWriter) record(row KeyValue, header *Header) []string {
+	return header.record(row, w.ValueFormatter, w.NullValue)
 }
 
 // WriteCSVByHeader writes CSV rows according the given header.
 // For header columns of csvHeader that are missing in results, output an empty value.
 // Fields of results that are absent in csvHeader are ignored.
+// To write rows by the same header many times, use CompileHeader and WriteRows.
 func (w *CSVWriter) WriteCSVByHeader(results []KeyValue, csvHeader CSVHeader) error {
-	result := KeyValue{}
-	for h := range csvHeader {
-		result[h] = ""
-	}
-	pts, err := allPointers([]KeyValue{result})
+	header, err := w.CompileHeader(csvHeader)
 	if err != nil {
 		return err
 	}
-	sort.Sort(pts)
-	keys := pts.Strings()
-
-	for _, result := range results {
-		for h := range csvHeader {
-			if _, exist := result[h]; !exist {
-				result[h] = ""
-			}
-		}
-		record := toRecord(result, keys)
-		if err := w.Write(record); err != nil {
-			return err
-		}
-	}
-	w.Flush()
-	if err := w.Error(); err != nil {
+	if err := w.WriteRows(results, header); err != nil {
 		return err
 	}
-	return nil
+	w.Flush()
+	return w.Error()
 }
 
 // WriteCSV writes CSV data.
@@ -124,14 +144,17 @@ func (w *CSVWriter) writeCSV(results []KeyValue) error {
 	}
 	sort.Sort(pts)
 	keys := pts.Strings()
-	header := w.getHeader(pts)
+	header, err := w.getHeader(pts)
+	if err != nil {
+		return err
+	}
 
 	if err := w.Write(header); err != nil {
 		return err
 	}
 
 	for _, result := range results {
-		record := toRecord(result, keys)
+		record := toRecord(result, keys, w.ValueFormatter, w.NullValue)
 		if err := w.Write(record); err != nil {
 			return err
 		}
@@ -153,10 +176,13 @@ func (w *CSVWriter) writeTransposedCSV(results []KeyValue) error {
 	}
 	sort.Sort(pts)
 	keys := pts.Strings()
-	header := w.getHeader(pts)
+	header, err := w.getHeader(pts)
+	if err != nil {
+		return err
+	}
 
 	for i, key := range keys {
-		record := toTransposedRecord(results, key, header[i])
+		record := toTransposedRecord(results, key, header[i], w.ValueFormatter, w.NullValue)
 		if err := w.Write(record); err != nil {
 			return err
 		}
@@ -187,41 +213,30 @@ func allPointers(results []KeyValue) (pointers pointers, err error) {
 	return
 }
 
-func (w *CSVWriter) getHeader(pointers pointers) []string {
-	switch w.HeaderStyle {
-	case JSONPointerStyle:
-		return pointers.Strings()
-	case SlashStyle:
-		return pointers.Slashes()
-	case DotNotationStyle:
-		return pointers.DotNotations(false)
-	case DotBracketStyle:
-		return pointers.DotNotations(true)
-	default:
-		return pointers.Strings()
-	}
+func (w *CSVWriter) getHeader(pointers pointers) ([]string, error) {
+	return formatHeader(pointers, keyFormatterOf(w.HeaderStyle, w.KeyFormatter), w.Renamer)
 }
 
-func toRecord(kv KeyValue, keys []string) []string {
+func toRecord(kv KeyValue, keys []string, formatter ValueFormatter, nullValue string) []string {
 	record := make([]string, 0, len(keys))
 	for _, key := range keys {
-		if value, ok := kv[key]; ok {
-			record = append(record, toString(value))
+		if value, ok := kv[key]; ok && value != nil {
+			record = append(record, formatValue(formatter, value))
 		} else {
-			record = append(record, "")
+			record = append(record, nullValue)
 		}
 	}
 	return record
 }
 
-func toTransposedRecord(results []KeyValue, key string, header string) []string {
+func toTransposedRecord(results []KeyValue, key string, header string, formatter ValueFormatter, nullValue string) []string {
 	record := make([]string, 0, len(results)+1)
 	record = append(record, header)
 	for _, result := range results {
-		if value, ok := result[key]; ok {
-			record = append(record, toString(value))
+		if value, ok := result[key]; ok && value != nil {
+			record = append(record, formatValue(formatter, value))
 		} else {
-			record = append(record, "")
+			record = append(record, nullValue)
 		}
 	}
 	return record
@@ -2,6 +2,8 @@ package json2csv_test
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"math"
 	"testing"
 
@@ -41,3 +43,22 @@ func TestKeyWithTrailingSpace(t *testing.T) {
 		t.Errorf("Expected %v, but %v", want, got)
 	}
 }
+
+func BenchmarkWriteCSV(b *testing.B) {
+	spec := json2csv.SyntheticSpec{Width: 50, Depth: 2, ArrayLen: 3, Sparsity: 0.3}
+	results, err := json2csv.JSON2CSV(spec.Records(1000), nil, math.MaxInt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, transpose := range []bool{false, true} {
+		b.Run(fmt.Sprintf("transpose=%v", transpose), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := json2csv.NewCSVWriter(io.Discard, json2csv.DotBracketStyle, transpose)
+				if err := w.WriteCSV(results); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// DefaultMaxMemoryKeys is the default number of keys that Deduplicator
+// holds in memory before spilling them to temporary files.
+const DefaultMaxMemoryKeys = 1000000
+
+// Deduplicator removes duplicate rows.
+//
+// Rows are identified by the values of Keys, or by the whole row if Keys is
+// empty. Only a digest of each key is stored, and digests exceeding
+// MaxMemoryKeys are moved to sorted index files on disk.
+//
+// To keep the last row of duplicates in a stream, call Scan for every row
+// before calling Keep for the rows in the same order.
+type Deduplicator struct {
+	Keys          []string
+	KeepLast      bool
+	MaxMemoryKeys int
+
+	// TempDir is the directory of the temporary files.
+	// If empty, the default directory for temporary files is used.
+	TempDir string
+
+	index    *digestIndex
+	scanned  uint64
+	kept     uint64
+	scanDone bool
+}
+
+// NewDeduplicator returns new Deduplicator with the key columns.
+func NewDeduplicator(keys []string, keepLast bool) *Deduplicator {
+	return &Deduplicator{
+		Keys:          keys,
+		KeepLast:      keepLast,
+		MaxMemoryKeys: DefaultMaxMemoryKeys,
+	}
+}
+
+// ParseUniqueKeys parses comma separated key columns like "/id,/type".
+func ParseUniqueKeys(spec string) ([]string, error) {
+	keys := []string{}
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		if _, err := jsonpointer.New(key); err != nil || key == "" {
+			return nil, fmt.Errorf("Invalid unique key %q", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Unique returns the rows without duplicates.
+func (d *Deduplicator) Unique(rows []KeyValue) ([]KeyValue, error) {
+	if d.KeepLast {
+		for _, row := range rows {
+			if err := d.Scan(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	results := make([]KeyValue, 0, len(rows))
+	for _, row := range rows {
+		ok, err := d.Keep(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			results = append(results, row)
+		}
+	}
+	return results, nil
+}
+
+// Scan records the row as the last one of its key.
+// It is needed only if KeepLast.
+func (d *Deduplicator) Scan(row KeyValue) error {
+	if d.kept > 0 {
+		return errors.New("Deduplicator: Scan after Keep")
+	}
+	d.scanned++
+	d.scanDone = true
+	return d.getIndex().Put(d.digest(row), d.scanned)
+}
+
+// Keep reports whether the row should be written.
+func (d *Deduplicator) Keep(row KeyValue) (bool, error) {
+	d.kept++
+	key := d.digest(row)
+	index := d.getIndex()
+	seq, found, err := index.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if d.KeepLast {
+		if !d.scanDone {
+			return false, errors.New("Deduplicator: Keep without Scan")
+		}
+		return found && seq == d.kept, nil
+	}
+	if found {
+		return false, nil
+	}
+	return true, index.Put(key, d.kept)
+}
+
+// Close removes the temporary files.
+func (d *Deduplicator) Close() error {
+	if d.index == nil {
+		return nil
+	}
+	return d.index.Close()
+}
+
+func (d *Deduplicator) getIndex() *digestIndex {
+	if d.index == nil {
+		d.index = &digestIndex{
+			maxMemory: d.MaxMemoryKeys,
+			tempDir:   d.TempDir,
+			memory:    map[digest]uint64{},
+		}
+	}
+	return d.index
+}
+
+// digest returns the digest of the key values of the row.
+func (d *Deduplicator) digest(row KeyValue) digest {
+	keys := d.Keys
+	if len(keys) == 0 {
+		keys = row.Keys()
+		sort.Strings(keys)
+	}
+
+	var b strings.Builder
+	for _, key := range keys {
+		if len(d.Keys) == 0 {
+			b.WriteString(key)
+			b.WriteString("\x00")
+		}
+		writeValueKey(&b, row[key])
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	var key digest
+	copy(key[:], sum[:])
+	return key
+}
+
+type digest [16]byte
+
+const (
+	indexRecordSize = 24 // digest and uint64 value
+	maxIndexRuns    = 8
+)
+
+// digestIndex maps digests to values. Entries exceeding maxMemory are
+// written to index files (runs) sorted by digest, and newer runs take
+// precedence over older ones.
+type digestIndex struct {
+	maxMemory int
+	tempDir   string
+	memory    map[digest]uint64
+	runs      []*os.File // oldest first
+}
+
+func (x *digestIndex) Get(key digest) (uint64, bool, error) {
+	if v, ok := x.memory[key]; ok {
+		return v, true, nil
+	}
+	for i := len(x.runs) - 1; i >= 0; i-- {
+		v, ok, err := searchRun(x.runs[i], key)
+		if err != nil || ok {
+			return v, ok, err
+		}
+	}
+	return 0, false, nil
+}
+
+func (x *digestIndex) Put(key digest, value uint64) error {
+	x.memory[key] = value
+	if x.maxMemory > 0 && len(x.memory) >= x.maxMemory {
+		return x.spill()
+	}
+	return nil
+}
+
+// spill writes the entries in memory to a new run.
+func (x *digestIndex) spill() error {
+	keys := make([]digest, 0, len(x.memory))
+	for key := range x.memory {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+
+	f, err := os.CreateTemp(x.tempDir, "json2csv-unique-*")
+	if err != nil {
+		return err
+	}
+	x.runs = append(x.runs, f)
+	w := bufio.NewWriter(f)
+	for _, key := range keys {
+		if err := writeIndexRecord(w, key, x.memory[key]); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	x.memory = map[digest]uint64{}
+
+	if len(x.runs) > maxIndexRuns {
+		return x.merge()
+	}
+	return nil
+}
+
+// merge merges all runs into one.
+func (x *digestIndex) merge() error {
+	f, err := os.CreateTemp(x.tempDir, "json2csv-unique-*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	type head struct {
+		r     *bufio.Reader
+		key   digest
+		value uint64
+		ok    bool
+	}
+	heads := make([]*head, len(x.runs))
+	for i, run := range x.runs {
+		if _, err := run.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		heads[i] = &head{r: bufio.NewReader(run)}
+	}
+	next := func(h *head) error {
+		var err error
+		h.key, h.value, err = readIndexRecord(h.r)
+		h.ok = err == nil
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	for _, h := range heads {
+		if err := next(h); err != nil {
+			return err
+		}
+	}
+
+	for {
+		// the smallest digest, the newest run wins
+		var min *head
+		for _, h := range heads {
+			if h.ok && (min == nil || bytes.Compare(h.key[:], min.key[:]) <= 0) {
+				min = h
+			}
+		}
+		if min == nil {
+			break
+		}
+		if err := writeIndexRecord(w, min.key, min.value); err != nil {
+			return err
+		}
+		key := min.key
+		for _, h := range heads {
+			if h.ok && h.key == key {
+				if err := next(h); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err := x.removeRuns(); err != nil {
+		return err
+	}
+	x.runs = []*os.File{f}
+	return nil
+}
+
+func (x *digestIndex) removeRuns() error {
+	var errs []error
+	for _, f := range x.runs {
+		errs = append(errs, f.Close(), os.Remove(f.Name()))
+	}
+	x.runs = nil
+	return errors.Join(errs...)
+}
+
+func (x *digestIndex) Close() error {
+	x.memory = map[digest]uint64{}
+	return x.removeRuns()
+}
+
+func writeIndexRecord(w io.Writer, key digest, value uint64) error {
+	var b [indexRecordSize]byte
+	copy(b[:], key[:])
+	binary.BigEndian.PutUint64(b[len(key):], value)
+	_, err := w.Write(b[:])
+	return err
+}
+
+func readIndexRecord(r io.Reader) (digest, uint64, error) {
+	var b [indexRecordSize]byte
+	var key digest
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return key, 0, errors.New("Corrupted index file")
+		}
+		return key, 0, err
+	}
+	copy(key[:], b[:])
+	return key, binary.BigEndian.Uint64(b[len(key):]), nil
+}
+
+// searchRun finds the key in the run by binary search.
+func searchRun(f *os.File, key digest) (uint64, bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	var b [indexRecordSize]byte
+	lo, hi := int64(0), info.Size()/indexRecordSize
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if _, err := f.ReadAt(b[:], mid*indexRecordSize); err != nil {
+			return 0, false, err
+		}
+		switch c := bytes.Compare(b[:len(key)], key[:]); {
+		case c == 0:
+			return binary.BigEndian.Uint64(b[len(key):]), true, nil
+		case c < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,102 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func testDedupRows() []KeyValue {
+	return []KeyValue{
+		{"/id": json.Number("1"), "/v": "a"},
+		{"/id": json.Number("2"), "/v": "b"},
+		{"/id": json.Number("1"), "/v": "c"},
+		{"/id": "1", "/v": "d"},
+		{"/id": json.Number("2"), "/v": "b"},
+		{"/v": "e"},
+		{"/id": nil, "/v": "f"},
+	}
+}
+
+var testDeduplicatorCases = []struct {
+	keys     []string
+	keepLast bool
+	expected []string
+}{
+	{[]string{"/id"}, false, []string{"a", "b", "d", "e"}},
+	{[]string{"/id"}, true, []string{"c", "d", "b", "f"}},
+	{[]string{"/id", "/v"}, false, []string{"a", "b", "c", "d", "e", "f"}},
+	{nil, false, []string{"a", "b", "c", "d", "e", "f"}},
+	{nil, true, []string{"a", "c", "d", "b", "e", "f"}},
+}
+
+func TestDeduplicator(t *testing.T) {
+	for _, maxMemoryKeys := range []int{0, 1, 2} {
+		for caseIndex, testCase := range testDeduplicatorCases {
+			dedup := NewDeduplicator(testCase.keys, testCase.keepLast)
+			dedup.MaxMemoryKeys = maxMemoryKeys
+			dedup.TempDir = t.TempDir()
+			rows, err := dedup.Unique(testDedupRows())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dedup.Close(); err != nil {
+				t.Fatal(err)
+			}
+			actual := []string{}
+			for _, row := range rows {
+				actual = append(actual, row["/v"].(string))
+			}
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("%d/%d: Expected %v, but %v", maxMemoryKeys, caseIndex, testCase.expected, actual)
+			}
+		}
+	}
+}
+
+func TestDeduplicatorManyKeys(t *testing.T) {
+	// more keys than maxIndexRuns to merge the index files
+	rows := []KeyValue{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, KeyValue{"/id": json.Number(strconv.Itoa(i % 30)), "/n": i})
+	}
+	for _, keepLast := range []bool{false, true} {
+		dedup := NewDeduplicator([]string{"/id"}, keepLast)
+		dedup.MaxMemoryKeys = 2
+		dedup.TempDir = t.TempDir()
+		results, err := dedup.Unique(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dedup.Close()
+		if len(results) != 30 {
+			t.Errorf("%v: Expected 30 rows, but %d", keepLast, len(results))
+			continue
+		}
+		for i, row := range results {
+			expected := i
+			if keepLast {
+				expected = 70 + i
+			}
+			if row["/n"] != expected {
+				t.Errorf("%v: Expected %d, but %v", keepLast, expected, row["/n"])
+			}
+		}
+	}
+}
+
+func TestParseUniqueKeys(t *testing.T) {
+	keys, err := ParseUniqueKeys("/id, /type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/id", "/type"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, but %v", expected, keys)
+	}
+	for caseIndex, spec := range []string{"", "id", "/id,"} {
+		if _, err := ParseUniqueKeys(spec); err == nil {
+			t.Errorf("%d: Expected error, but nil", caseIndex)
+		}
+	}
+}
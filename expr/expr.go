@@ -0,0 +1,411 @@
+// Package expr implements a small expression language over JSON Pointer paths.
+//
+// An expression consists of literals (numbers, "strings", true, false, null),
+// paths (JSON Pointers such as /user/name), arithmetic (+ - * / %),
+// comparisons (== != < <= > >=), regular expression matches (=~ !~),
+// boolean logic (&& || ! and or not), parentheses and function calls such as
+// exists(/path), between(/age, 18, 65) and concat(/first, " ", /last).
+//
+// "/" is the division operator where an operand ends, and starts a path
+// otherwise, so put spaces around operators: /price / /qty.
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Resolver resolves values of paths.
+type Resolver interface {
+	// Resolve returns the value of the pointer and reports whether it exists.
+	Resolve(pointer jsonpointer.JSONPointer) (interface{}, bool)
+}
+
+// ResolverFunc is an adapter to use ordinary functions as Resolver.
+type ResolverFunc func(pointer jsonpointer.JSONPointer) (interface{}, bool)
+
+// Resolve calls f(pointer).
+func (f ResolverFunc) Resolve(pointer jsonpointer.JSONPointer) (interface{}, bool) {
+	return f(pointer)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression.
+// The result is nil, bool, string, float64, int64 or a value returned by the
+// Resolver.
+func (e *Expr) Eval(r Resolver) (interface{}, error) {
+	return e.root.eval(r)
+}
+
+// Paths returns all paths referred by the expression.
+func (e *Expr) Paths() []jsonpointer.JSONPointer {
+	var paths []jsonpointer.JSONPointer
+	walk(e.root, func(n node) {
+		switch n := n.(type) {
+		case *pathNode:
+			paths = append(paths, n.pointer)
+		case *existsNode:
+			paths = append(paths, n.pointer)
+		}
+	})
+	return paths
+}
+
+// Truthy reports whether the value is considered as true.
+// nil, false, "" and 0 are false, and others are true.
+func Truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if n, ok := toNumber(v); ok {
+		return n != 0
+	}
+	return true
+}
+
+type node interface {
+	eval(r Resolver) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(r Resolver) (interface{}, error) {
+	return n.value, nil
+}
+
+type pathNode struct {
+	pointer jsonpointer.JSONPointer
+}
+
+func (n *pathNode) eval(r Resolver) (interface{}, error) {
+	v, _ := r.Resolve(n.pointer)
+	return v, nil
+}
+
+type existsNode struct {
+	pointer jsonpointer.JSONPointer
+}
+
+func (n *existsNode) eval(r Resolver) (interface{}, error) {
+	_, ok := r.Resolve(n.pointer)
+	return ok, nil
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) eval(r Resolver) (interface{}, error) {
+	v, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	return !Truthy(v), nil
+}
+
+type logicalNode struct {
+	op   string
+	x, y node
+}
+
+func (n *logicalNode) eval(r Resolver) (interface{}, error) {
+	x, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if Truthy(x) == (n.op == "||") {
+		return Truthy(x), nil
+	}
+	y, err := n.y.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	return Truthy(y), nil
+}
+
+type compareNode struct {
+	op   string
+	x, y node
+}
+
+func (n *compareNode) eval(r Resolver) (interface{}, error) {
+	x, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, x, y), nil
+}
+
+type arithNode struct {
+	op   string
+	x, y node
+}
+
+// eval returns nil if either operand is not a number, or on division by zero.
+// Integers are calculated exactly as int64 unless the result overflows or is
+// not an integer.
+func (n *arithNode) eval(r Resolver) (interface{}, error) {
+	x, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	nx, ok := toNumber(x)
+	if !ok {
+		return nil, nil
+	}
+	ny, ok := toNumber(y)
+	if !ok {
+		return nil, nil
+	}
+	if (n.op == "/" || n.op == "%") && ny == 0 {
+		return nil, nil
+	}
+	if ix, ok := toInt(x); ok {
+		if iy, ok := toInt(y); ok {
+			if v, ok := intArith(n.op, ix, iy); ok {
+				return v, nil
+			}
+		}
+	}
+
+	switch n.op {
+	case "+":
+		return nx + ny, nil
+	case "-":
+		return nx - ny, nil
+	case "*":
+		return nx * ny, nil
+	case "/":
+		return nx / ny, nil
+	case "%":
+		return math.Mod(nx, ny), nil
+	}
+	return nil, fmt.Errorf("Unknown operator %q", n.op)
+}
+
+// intArith calculates integers. It reports false if the result overflows
+// or is not an integer. y must not be 0 for "/" and "%".
+func intArith(op string, x, y int64) (int64, bool) {
+	switch op {
+	case "+":
+		v := x + y
+		return v, (y >= 0 && v >= x) || (y < 0 && v < x)
+	case "-":
+		v := x - y
+		return v, (y >= 0 && v <= x) || (y < 0 && v > x)
+	case "*":
+		if x == 0 || y == 0 {
+			return 0, true
+		}
+		v := x * y
+		return v, v/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64)
+	case "/":
+		if x%y != 0 || (x == math.MinInt64 && y == -1) {
+			return 0, false
+		}
+		return x / y, true
+	case "%":
+		if y == -1 {
+			return 0, true
+		}
+		return x % y, true
+	}
+	return 0, false
+}
+
+type matchNode struct {
+	x      node
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n *matchNode) eval(r Resolver) (interface{}, error) {
+	x, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil {
+		return n.negate, nil
+	}
+	return n.re.MatchString(toString(x)) != n.negate, nil
+}
+
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) eval(r Resolver) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *notNode:
+		walk(n.x, fn)
+	case *logicalNode:
+		walk(n.x, fn)
+		walk(n.y, fn)
+	case *compareNode:
+		walk(n.x, fn)
+		walk(n.y, fn)
+	case *arithNode:
+		walk(n.x, fn)
+		walk(n.y, fn)
+	case *matchNode:
+		walk(n.x, fn)
+	case *callNode:
+		for _, arg := range n.args {
+			walk(arg, fn)
+		}
+	}
+}
+
+// compare compares values. Numbers are compared numerically, and strings are
+// compared lexicographically. Values of different types are only unequal.
+func compare(op string, x, y interface{}) bool {
+	var c int
+	if nx, ok := toNumber(x); ok {
+		ny, ok := toNumber(y)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case nx < ny:
+			c = -1
+		case nx > ny:
+			c = 1
+		}
+	} else if sx, ok := x.(string); ok {
+		sy, ok := y.(string)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case sx < sy:
+			c = -1
+		case sx > sy:
+			c = 1
+		}
+	} else {
+		equal := reflect.DeepEqual(x, y)
+		switch op {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		default:
+			return false
+		}
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// toNumber converts numeric values to float64.
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// toInt converts integers to int64. Floating point numbers are integers if
+// they are whole and exact, such as the literal 2.
+func toInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case json.Number:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		return n, err == nil
+	case float64:
+		return int64(v), v == math.Trunc(v) && math.Abs(v) <= 1<<53
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
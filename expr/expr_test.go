@@ -0,0 +1,184 @@
+package expr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+var testRecord = map[string]interface{}{
+	"id":     json.Number("42"),
+	"big":    json.Number("9007199254740993"),
+	"name":   "foo bar",
+	"price":  json.Number("9.5"),
+	"active": true,
+	"none":   nil,
+	"user": map[string]interface{}{
+		"age":  json.Number("30"),
+		"tags": []interface{}{"a", "b"},
+	},
+}
+
+var testRecordResolver = ResolverFunc(func(pointer jsonpointer.JSONPointer) (interface{}, bool) {
+	return pointer.Lookup(testRecord)
+})
+
+var testEvalCases = []struct {
+	expr     string
+	expected interface{}
+}{
+	{`/id == 42`, true},
+	{`/id != 42`, false},
+	{`/id > 41.5`, true},
+	{`/price <= 9.5`, true},
+	{`/price < 9`, false},
+	{`/name == "foo bar"`, true},
+	{`/name == 'foo bar'`, true},
+	{`/name > "foo"`, true},
+	{`/name == 42`, false},
+	{`/name != 42`, true},
+	{`/active == true`, true},
+	{`/active`, true},
+	{`!/active`, false},
+	{`not /active`, false},
+	{`/none == null`, true},
+	{`/missing == null`, true},
+	{`/user/tags/1 == "b"`, true},
+	{`/user/age >= 18 && /user/age < 65`, true},
+	{`/user/age >= 18 and /user/age < 20`, false},
+	{`/id == 1 || /id == 42`, true},
+	{`/id == 1 or /id == 2`, false},
+	{`!(/id == 1 || /id == 2) && /active`, true},
+	{`exists(/none)`, true},
+	{`exists(/missing)`, false},
+	{`!exists(/user/tags/2)`, true},
+	{`/name =~ "^foo"`, true},
+	{`/name =~ "^bar"`, false},
+	{`/name !~ "^bar"`, true},
+	{`/id =~ "^4"`, true},
+	{`/missing =~ ".*"`, false},
+	{`between(/user/age, 18, 30)`, true},
+	{`between(/price, 10, 20)`, false},
+	{`between(/name, "a", "z")`, true},
+	{`-1 < 0`, true},
+	{`1.5e1 == 15`, true},
+	{`"a\"b" == 'a"b'`, true},
+	{`/user/tags/0 == "a" && (/missing || /id == 42)`, true},
+
+	// arithmetic
+	{`/price * 2`, 19.0},
+	{`/price*2`, 19.0},
+	{`/id / 2`, int64(21)},
+	{`/id / /price > 4`, true},
+	{`/id % 5`, int64(2)},
+	{`1 + 2 * 3 - 4`, int64(3)},
+	{`(1 + 2) * 3`, int64(9)},
+	{`-/id`, int64(-42)},
+	{`- -1`, 1.0},
+	{`2 - -1`, int64(3)},
+	{`/id / 0`, nil},
+	{`/name * 2`, nil},
+	{`/missing + 1`, nil},
+	{`/id + 1 == 43`, true},
+	{`/id / 4`, 10.5},
+	{`/big + 0`, int64(9007199254740993)},
+	{`/big * 1 - 1`, int64(9007199254740992)},
+	{`/big * 1024`, 9007199254740993.0 * 1024},
+	{`/big / 3`, int64(3002399751580331)},
+	{`/big % 10`, int64(3)},
+	{`/id + 0.5`, 42.5},
+	{`/id * 1.5`, 63.0},
+
+	// functions
+	{`coalesce(/missing, /none, /name)`, "foo bar"},
+	{`coalesce(/missing)`, nil},
+	{`if(/active, "yes", "no")`, "yes"},
+	{`concat(/name, "-", /id, /missing)`, "foo bar-42"},
+	{`upper(/name)`, "FOO BAR"},
+	{`lower("ABC")`, "abc"},
+	{`trim("  a ")`, "a"},
+	{`length(/name)`, 7.0},
+	{`substr(/name, 4)`, "bar"},
+	{`substr(/name, 0, 3)`, "foo"},
+	{`substr(/name, 5, 100)`, "ar"},
+	{`replace(/name, "o", "0")`, "f00 bar"},
+	{`upper(/missing)`, nil},
+	{`abs(-2.5)`, 2.5},
+	{`floor(/price)`, 9.0},
+	{`ceil(/price)`, 10.0},
+	{`round(/price)`, 10.0},
+	{`round(2.345, 2)`, 2.35},
+	{`min(3, /id, 1.5)`, 1.5},
+	{`max(3, /id, 1.5)`, json.Number("42")},
+	{`number("1.5") + 1`, 2.5},
+	{`date("2024-03-05T10:20:30Z")`, "2024-03-05"},
+	{`year("2024-03-05")`, 2024.0},
+	{`month("2024-03-05")`, 3.0},
+	{`day(0)`, 1.0},
+	{`unix("1970-01-02T00:00:00Z")`, 86400.0},
+	{`format_time(86400, "2006/01/02 15:04")`, "1970/01/02 00:00"},
+	{`days_between("2024-03-01", "2024-03-05")`, 4.0},
+}
+
+func TestEval(t *testing.T) {
+	for caseIndex, testCase := range testEvalCases {
+		e, err := Parse(testCase.expr)
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			continue
+		}
+		actual, err := e.Eval(testRecordResolver)
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+		} else if actual != testCase.expected {
+			t.Errorf("%d: %s: Expected %v, but %v", caseIndex, testCase.expr, testCase.expected, actual)
+		}
+	}
+}
+
+var testParseErrorCases = []struct {
+	expr string
+	err  string
+}{
+	{`/id ==`, `Unexpected end of expression at 6`},
+	{`/id == 1)`, `Unexpected ")" at 8`},
+	{`(/id == 1`, `Unexpected end of expression at 9`},
+	{`/id = 1`, `Unexpected character '=' at 4`},
+	{`/id +`, `Unexpected end of expression at 5`},
+	{`/name =~ /pattern`, `Expected a regular expression string at 9`},
+	{`/name =~ "("`, "error parsing regexp: missing closing ): `(`"},
+	{`exists("x")`, `Expected a path for exists at 7`},
+	{`unknown(/id)`, `Unknown function "unknown" at 0`},
+	{`between(/id, 1)`, `Wrong number of arguments for between: 2 at 0`},
+	{`"abc`, `Unterminated string at 0`},
+	{`/id == 1 1`, `Unexpected "1" at 9`},
+}
+
+func TestParseError(t *testing.T) {
+	for caseIndex, testCase := range testParseErrorCases {
+		_, err := Parse(testCase.expr)
+		if err == nil {
+			t.Errorf("%d: Expected error %v, but nil", caseIndex, testCase.err)
+		} else if err.Error() != testCase.err {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.err, err)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	e := MustParse(`/a == 1 && exists(/b/c) || between(/d, /e, 3)`)
+	actual := []string{}
+	for _, p := range e.Paths() {
+		actual = append(actual, p.String())
+	}
+	expected := []string{"/a", "/b/c", "/d", "/e"}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %v, but %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected %v, but %v", expected, actual)
+		}
+	}
+}
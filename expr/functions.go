@@ -0,0 +1,263 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type function struct {
+	minArgs int
+	maxArgs int // -1 means variadic
+	call    func(args []interface{}) (interface{}, error)
+}
+
+// functions are built-in functions except exists, which takes a path itself.
+// Most functions return null if an argument is null.
+var functions = map[string]*function{
+	// between(x, min, max) reports whether min <= x <= max.
+	"between": {3, 3, func(args []interface{}) (interface{}, error) {
+		return compare(">=", args[0], args[1]) && compare("<=", args[0], args[2]), nil
+	}},
+
+	// coalesce(x, ...) returns the first non-null value.
+	"coalesce": {1, -1, func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+
+	// if(cond, then, else) returns then if cond is true, otherwise else.
+	"if": {3, 3, func(args []interface{}) (interface{}, error) {
+		if Truthy(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	}},
+
+	// string functions
+
+	// concat(x, ...) concatenates values as strings. null is empty.
+	"concat": {1, -1, func(args []interface{}) (interface{}, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			if arg != nil {
+				b.WriteString(toString(arg))
+			}
+		}
+		return b.String(), nil
+	}},
+	"upper": stringFunction(strings.ToUpper),
+	"lower": stringFunction(strings.ToLower),
+	"trim":  stringFunction(strings.TrimSpace),
+	"length": {1, 1, nullable(func(args []interface{}) (interface{}, error) {
+		return float64(utf8.RuneCountInString(toString(args[0]))), nil
+	})},
+	// substr(s, start[, length]) returns the substring. start is 0-origin.
+	"substr": {2, 3, nullable(func(args []interface{}) (interface{}, error) {
+		runes := []rune(toString(args[0]))
+		start, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		start = clamp(start, 0, len(runes))
+		end := len(runes)
+		if len(args) > 2 {
+			length, err := intArg(args[2])
+			if err != nil {
+				return nil, err
+			}
+			end = clamp(start+length, start, len(runes))
+		}
+		return string(runes[start:end]), nil
+	})},
+	"replace": {3, 3, nullable(func(args []interface{}) (interface{}, error) {
+		return strings.ReplaceAll(toString(args[0]), toString(args[1]), toString(args[2])), nil
+	})},
+
+	// math functions
+
+	"abs":   mathFunction(math.Abs),
+	"floor": mathFunction(math.Floor),
+	"ceil":  mathFunction(math.Ceil),
+	"sqrt":  mathFunction(math.Sqrt),
+	// round(x[, digits]) rounds half away from zero.
+	"round": {1, 2, nullable(func(args []interface{}) (interface{}, error) {
+		n, err := numberArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		digits := 0
+		if len(args) > 1 {
+			if digits, err = intArg(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		p := math.Pow(10, float64(digits))
+		return math.Round(n*p) / p, nil
+	})},
+	"min": {1, -1, nullable(func(args []interface{}) (interface{}, error) {
+		return extremum(args, "<")
+	})},
+	"max": {1, -1, nullable(func(args []interface{}) (interface{}, error) {
+		return extremum(args, ">")
+	})},
+	"number": {1, 1, nullable(func(args []interface{}) (interface{}, error) {
+		return numberArg(args[0])
+	})},
+
+	// date functions
+	// Times are RFC 3339 strings or UNIX time in seconds.
+
+	// format_time(t, layout) formats the time with Go's layout.
+	"format_time": {2, 2, nullable(func(args []interface{}) (interface{}, error) {
+		t, err := timeArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(toString(args[1])), nil
+	})},
+	"date": timeFunction(func(t time.Time) interface{} {
+		return t.Format("2006-01-02")
+	}),
+	"year": timeFunction(func(t time.Time) interface{} {
+		return float64(t.Year())
+	}),
+	"month": timeFunction(func(t time.Time) interface{} {
+		return float64(t.Month())
+	}),
+	"day": timeFunction(func(t time.Time) interface{} {
+		return float64(t.Day())
+	}),
+	"unix": timeFunction(func(t time.Time) interface{} {
+		return float64(t.Unix())
+	}),
+	// days_between(t1, t2) returns days from t1 to t2.
+	"days_between": {2, 2, nullable(func(args []interface{}) (interface{}, error) {
+		t1, err := timeArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		t2, err := timeArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return t2.Sub(t1).Hours() / 24, nil
+	})},
+}
+
+func lookupFunction(name string, nargs int) (*function, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown function %q", name)
+	}
+	if nargs < fn.minArgs || (fn.maxArgs >= 0 && nargs > fn.maxArgs) {
+		return nil, fmt.Errorf("Wrong number of arguments for %s: %d", name, nargs)
+	}
+	return fn, nil
+}
+
+// nullable wraps fn to return null if any argument is null.
+func nullable(fn func(args []interface{}) (interface{}, error)) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+		}
+		return fn(args)
+	}
+}
+
+func stringFunction(fn func(string) string) *function {
+	return &function{1, 1, nullable(func(args []interface{}) (interface{}, error) {
+		return fn(toString(args[0])), nil
+	})}
+}
+
+func mathFunction(fn func(float64) float64) *function {
+	return &function{1, 1, nullable(func(args []interface{}) (interface{}, error) {
+		n, err := numberArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(n), nil
+	})}
+}
+
+func timeFunction(fn func(time.Time) interface{}) *function {
+	return &function{1, 1, nullable(func(args []interface{}) (interface{}, error) {
+		t, err := timeArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(t), nil
+	})}
+}
+
+func extremum(args []interface{}, op string) (interface{}, error) {
+	result := args[0]
+	for _, arg := range args[1:] {
+		if compare(op, arg, result) {
+			result = arg
+		}
+	}
+	return result, nil
+}
+
+func numberArg(v interface{}) (float64, error) {
+	if n, ok := toNumber(v); ok {
+		return n, nil
+	}
+	if s, ok := v.(string); ok {
+		if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("Not a number: %v", v)
+}
+
+func intArg(v interface{}) (int, error) {
+	n, err := numberArg(v)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) {
+		return 0, fmt.Errorf("Not an integer: %v", v)
+	}
+	return int(n), nil
+}
+
+func timeArg(v interface{}) (time.Time, error) {
+	if n, ok := toNumber(v); ok {
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, errors.New("Not a time")
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Not a time: %q", s)
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
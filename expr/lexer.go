@@ -0,0 +1,176 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenPath
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators ordered by length to match the longest one
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "+", "-", "*", "/", "%",
+}
+
+// isPathRune reports whether r can be a part of a path.
+func isPathRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()!=<>,&|"'+*%`, r)
+}
+
+type lexer struct {
+	src string
+	pos int
+
+	// afterOperand is true if the previous token ends an operand,
+	// so "/" is the division operator rather than the start of a path.
+	afterOperand bool
+}
+
+func (l *lexer) next() (token, error) {
+	tok, err := l.scan()
+	if err != nil {
+		return tok, err
+	}
+	switch tok.kind {
+	case tokenNumber, tokenString, tokenPath, tokenRParen:
+		l.afterOperand = true
+	case tokenIdent:
+		l.afterOperand = tok.value == "true" || tok.value == "false" || tok.value == "null"
+	default:
+		l.afterOperand = false
+	}
+	return tok, nil
+}
+
+func (l *lexer) scan() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokenLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokenRParen, text: ")", pos: start}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.lexString(c)
+	case c == '/' && !l.afterOperand:
+		return l.lexPath()
+	case c == '.' || (c >= '0' && c <= '9'):
+		return l.lexNumber()
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		return token{kind: tokenIdent, text: text, value: text, pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, value: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("Unexpected character %q at %d", c, start)
+}
+
+func (l *lexer) lexPath() (token, error) {
+	start := l.pos
+	for _, r := range l.src[l.pos:] {
+		if !isPathRune(r) {
+			break
+		}
+		l.pos += len(string(r))
+	}
+	text := l.src[start:l.pos]
+	return token{kind: tokenPath, text: text, value: text, pos: start}, nil
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' ||
+			((c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E')) {
+			l.pos++
+			continue
+		}
+		break
+	}
+	text := l.src[start:l.pos]
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return token{}, fmt.Errorf("Invalid number %q at %d", text, start)
+	}
+	return token{kind: tokenNumber, text: text, value: text, pos: start}, nil
+}
+
+func (l *lexer) lexString(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case quote:
+			l.pos++
+			return token{kind: tokenString, text: l.src[start:l.pos], value: b.String(), pos: start}, nil
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("Unterminated string at %d", start)
+			}
+			switch e := l.src[l.pos+1]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(e)
+			}
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("Unterminated string at %d", start)
+}
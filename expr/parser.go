@@ -0,0 +1,311 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Parse parses the expression.
+func Parse(src string) (*Expr, error) {
+	p := &parser{lexer: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("Unexpected %s at %d", p.tok, p.tok.pos)
+}
+
+// isOperator reports whether the current token is one of the operators,
+// including keyword operators.
+func (p *parser) isOperator(ops ...string) bool {
+	if p.tok.kind != tokenOperator && p.tok.kind != tokenIdent {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.value == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind) error {
+	if p.tok.kind != kind {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// or := and (("||" | "or") and)*
+func (p *parser) parseOr() (node, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||", "or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{op: "||", x: x, y: y}
+	}
+	return x, nil
+}
+
+// and := not (("&&" | "and") not)*
+func (p *parser) parseAnd() (node, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&", "and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{op: "&&", x: x, y: y}
+	}
+	return x, nil
+}
+
+// not := ("!" | "not") not | comparison
+func (p *parser) parseNot() (node, error) {
+	if p.isOperator("!", "not") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+// comparison := additive (("==" | "!=" | "<" | "<=" | ">" | ">=") additive | ("=~" | "!~") string)?
+func (p *parser) parseComparison() (node, error) {
+	x, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isOperator("==", "!=", "<", "<=", ">", ">="):
+		op := p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, x: x, y: y}, nil
+	case p.isOperator("=~", "!~"):
+		negate := p.tok.value == "!~"
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenString {
+			return nil, fmt.Errorf("Expected a regular expression string at %d", p.tok.pos)
+		}
+		re, err := regexp.Compile(p.tok.value)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &matchNode{x: x, re: re, negate: negate}, nil
+	}
+	return x, nil
+}
+
+// additive := multiplicative (("+" | "-") multiplicative)*
+func (p *parser) parseAdditive() (node, error) {
+	x, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenOperator && p.isOperator("+", "-") {
+		op := p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		x = &arithNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+// multiplicative := unary (("*" | "/" | "%") unary)*
+func (p *parser) parseMultiplicative() (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenOperator && p.isOperator("*", "/", "%") {
+		op := p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &arithNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+// unary := "-" unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokenOperator && p.tok.value == "-" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := x.(*literalNode); ok {
+			if n, ok := lit.value.(float64); ok {
+				return &literalNode{-n}, nil
+			}
+		}
+		return &arithNode{op: "-", x: &literalNode{float64(0)}, y: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primary := number | string | path | "true" | "false" | "null" | call | "(" or ")"
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenNumber:
+		n, _ := strconv.ParseFloat(tok.value, 64)
+		return &literalNode{n}, p.advance()
+	case tokenString:
+		return &literalNode{tok.value}, p.advance()
+	case tokenPath:
+		pointer, err := jsonpointer.New(tok.value)
+		if err != nil {
+			return nil, err
+		}
+		return &pathNode{pointer}, p.advance()
+	case tokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(tokenRParen)
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return &literalNode{true}, p.advance()
+		case "false":
+			return &literalNode{false}, p.advance()
+		case "null":
+			return &literalNode{nil}, p.advance()
+		}
+		return p.parseCall()
+	}
+	return nil, p.unexpected()
+}
+
+// call := ident "(" (or ("," or)*)? ")"
+func (p *parser) parseCall() (node, error) {
+	name := p.tok.value
+	pos := p.tok.pos
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+
+	if name == "exists" {
+		if p.tok.kind != tokenPath {
+			return nil, fmt.Errorf("Expected a path for exists at %d", p.tok.pos)
+		}
+		pointer, err := jsonpointer.New(p.tok.value)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &existsNode{pointer}, p.expect(tokenRParen)
+	}
+
+	args := []node{}
+	for p.tok.kind != tokenRParen {
+		if len(args) > 0 {
+			if err := p.expect(tokenComma); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	fn, err := lookupFunction(name, len(args))
+	if err != nil {
+		return nil, fmt.Errorf("%w at %d", err, pos)
+	}
+	return &callNode{name: name, fn: fn, args: args}, nil
+}
@@ -0,0 +1,46 @@
+package json2csv
+
+import (
+	"github.com/yukithm/json2csv/expr"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Filter decides which records are converted.
+type Filter interface {
+	// Match reports whether the record should be converted.
+	Match(record interface{}) (bool, error)
+}
+
+// FilterFunc is an adapter to use ordinary functions as Filter.
+type FilterFunc func(record interface{}) (bool, error)
+
+// Match calls f(record).
+func (f FilterFunc) Match(record interface{}) (bool, error) {
+	return f(record)
+}
+
+// ExprFilter is a Filter which evaluates an expression against each record.
+// Paths in the expression are JSON Pointers relative to the record.
+type ExprFilter struct {
+	expr *expr.Expr
+}
+
+// NewExprFilter parses the expression and returns new ExprFilter.
+func NewExprFilter(expression string) (*ExprFilter, error) {
+	e, err := expr.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &ExprFilter{e}, nil
+}
+
+// Match reports whether the expression is true for the record.
+func (f *ExprFilter) Match(record interface{}) (bool, error) {
+	v, err := f.expr.Eval(expr.ResolverFunc(func(pointer jsonpointer.JSONPointer) (interface{}, bool) {
+		return pointer.Lookup(record)
+	}))
+	if err != nil {
+		return false, err
+	}
+	return expr.Truthy(v), nil
+}
@@ -7,12 +7,16 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/yukithm/json2csv/jsonpointer"
 )
 
 var jsonNumberType = reflect.TypeOf(json.Number(""))
 
+// errInvalidValue is the error of nil, the same as the reflection path.
+var errInvalidValue = fmt.Errorf("Unknown kind: %s", reflect.Invalid)
+
 type mapKeys []reflect.Value
 
 func (k mapKeys) Len() int           { return len(k) }
@@ -28,6 +32,12 @@ func sortedMapKeys(v reflect.Value) []reflect.Value {
 // KeyValue represents key(path)/value map.
 type KeyValue map[string]interface{}
 
+// Resolve returns the value of the key, which implements expr.Resolver.
+func (kv KeyValue) Resolve(pointer jsonpointer.JSONPointer) (interface{}, bool) {
+	v, ok := kv[pointer.String()]
+	return v, ok
+}
+
 // Keys returns all keys.
 func (kv KeyValue) Keys() []string {
 	keys := make([]string, 0, len(kv))
@@ -37,17 +47,193 @@ func (kv KeyValue) Keys() []string {
 	return keys
 }
 
-func flatten(obj interface{}, sliceLen int) (KeyValue, error) {
-	f := make(KeyValue, 0)
+// ArrayPolicy represents how arrays are flattened.
+type ArrayPolicy uint
+
+// Array policies
+const (
+	// a column for each element: "/tags/0", "/tags/1"
+	IndexArray ArrayPolicy = iota
+
+	// primitive elements joined with the separator: "a,b"
+	JoinArray
+
+	// compact JSON string: "[""a"",""b""]"
+	JSONArray
+
+	// the number of elements: 2
+	CountArray
+)
+
+// DefaultArraySeparator is the default separator of JoinArray.
+const DefaultArraySeparator = ","
+
+// FlattenOptions controls how nested values are flattened.
+type FlattenOptions struct {
+	// SliceLen limits the number of elements of each array.
+	SliceLen int
+
+	// MaxDepth stops flattening at the depth, and writes deeper objects and
+	// arrays as compact JSON strings. 0 means unlimited.
+	MaxDepth int
+
+	// MaxDepthOverrides overrides MaxDepth for the subtree of each JSON
+	// Pointer. The depth is relative to the pointer, and "*" matches any
+	// token.
+	MaxDepthOverrides map[string]int
+
+	// ArrayPolicy is how arrays are flattened.
+	ArrayPolicy ArrayPolicy
+
+	// ArraySeparator separates elements of JoinArray. Empty means
+	// DefaultArraySeparator.
+	ArraySeparator string
+
+	// ArrayPolicyOverrides overrides ArrayPolicy for arrays at each JSON
+	// Pointer. "*" matches any token.
+	ArrayPolicyOverrides map[string]ArrayPolicy
+
+	// Filter drops records before flattening. nil means all records.
+	Filter Filter
+
+	// Columns are added to each row after flattening, in order.
+	Columns []*ComputedColumn
+
+	// Melt converts columns into rows after Columns are added.
+	// nil means no conversion.
+	Melt *Melt
+}
+
+// NewFlattenOptions returns new FlattenOptions with no limits.
+func NewFlattenOptions() *FlattenOptions {
+	return &FlattenOptions{
+		SliceLen: math.MaxInt,
+	}
+}
+
+type flattener struct {
+	sliceLen        int
+	maxDepth        int
+	depthOverrides  []depthOverride
+	arrayPolicy     ArrayPolicy
+	arraySeparator  string
+	policyOverrides []policyOverride
+	filter          Filter
+	columns         []*ComputedColumn
+	melt            *Melt
+
+	// reflectOnly disables flattenDecoded, to compare with it in tests.
+	reflectOnly bool
+}
+
+type depthOverride struct {
+	pattern jsonpointer.JSONPointer
+	depth   int
+}
+
+type policyOverride struct {
+	pattern jsonpointer.JSONPointer
+	policy  ArrayPolicy
+}
+
+func newFlattener(opts *FlattenOptions) (*flattener, error) {
+	if opts == nil {
+		opts = NewFlattenOptions()
+	}
+	f := &flattener{
+		sliceLen:       opts.SliceLen,
+		maxDepth:       opts.MaxDepth,
+		arrayPolicy:    opts.ArrayPolicy,
+		arraySeparator: opts.ArraySeparator,
+		filter:         opts.Filter,
+		columns:        opts.Columns,
+		melt:           opts.Melt,
+	}
+	if f.arraySeparator == "" {
+		f.arraySeparator = DefaultArraySeparator
+	}
+	for pointer, depth := range opts.MaxDepthOverrides {
+		pattern, err := jsonpointer.New(pointer)
+		if err != nil {
+			return nil, err
+		}
+		f.depthOverrides = append(f.depthOverrides, depthOverride{pattern, depth})
+	}
+	for pointer, policy := range opts.ArrayPolicyOverrides {
+		pattern, err := jsonpointer.New(pointer)
+		if err != nil {
+			return nil, err
+		}
+		f.policyOverrides = append(f.policyOverrides, policyOverride{pattern, policy})
+	}
+
+	// prefer specific patterns to wildcards
+	sort.Slice(f.depthOverrides, func(i, j int) bool {
+		return lessPattern(f.depthOverrides[i].pattern, f.depthOverrides[j].pattern)
+	})
+	sort.Slice(f.policyOverrides, func(i, j int) bool {
+		return lessPattern(f.policyOverrides[i].pattern, f.policyOverrides[j].pattern)
+	})
+	return f, nil
+}
+
+// policyOf returns the ArrayPolicy of the array at the key.
+func (f *flattener) policyOf(key jsonpointer.JSONPointer) ArrayPolicy {
+	for _, o := range f.policyOverrides {
+		if matchPointer(o.pattern, key) {
+			return o.policy
+		}
+	}
+	return f.arrayPolicy
+}
+
+// depthLimit returns the depth at which flattening stops under the key.
+// -1 means unlimited.
+func (f *flattener) depthLimit(key jsonpointer.JSONPointer, limit int) int {
+	for _, o := range f.depthOverrides {
+		if matchPointer(o.pattern, key) {
+			return key.Len() + o.depth
+		}
+	}
+	return limit
+}
+
+// match reports whether the record passes the filter.
+func (f *flattener) match(record reflect.Value) (bool, error) {
+	if f.filter == nil {
+		return true, nil
+	}
+	return f.filter.Match(record.Interface())
+}
+
+func flatten(obj interface{}, f *flattener) (KeyValue, error) {
+	out := make(KeyValue, 0)
 	key := jsonpointer.JSONPointer{}
-	if err := _flatten(f, obj, key, sliceLen); err != nil {
+	limit := -1
+	if f.maxDepth > 0 {
+		limit = f.maxDepth
+	}
+	if err := f.flatten(out, obj, key, limit); err != nil {
 		return nil, err
 	}
-	return f, nil
+	for _, column := range f.columns {
+		if err := column.Apply(out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
 }
 
-func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, sliceLen int) error {
+func (f *flattener) flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, limit int) error {
 	value, ok := obj.(reflect.Value)
+	if !f.reflectOnly {
+		if ok && value.IsValid() && value.CanInterface() {
+			obj = value.Interface()
+		}
+		if ok, err := f.flattenDecoded(out, obj, key, key.String(), key.Len(), limit); ok {
+			return err
+		}
+	}
 	if !ok {
 		value = reflect.ValueOf(obj)
 	}
@@ -64,10 +250,29 @@ func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, sliceL
 	}
 
 	switch value.Kind() {
-	case reflect.Map:
-		_flattenMap(out, value, key, sliceLen)
-	case reflect.Slice:
-		_flattenSlice(out, value, key, sliceLen)
+	case reflect.Map, reflect.Slice:
+		limit = f.depthLimit(key, limit)
+		if limit >= 0 && key.Len() >= limit {
+			return f.flattenJSON(out, value, key)
+		}
+		if value.Kind() == reflect.Map {
+			f.flattenMap(out, value, key, limit)
+			return nil
+		}
+		switch f.policyOf(key) {
+		case JoinArray:
+			if isPrimitiveArray(value) {
+				f.flattenJoinedSlice(out, value, key)
+			} else {
+				f.flattenSlice(out, value, key, limit)
+			}
+		case JSONArray:
+			return f.flattenJSON(out, value, key)
+		case CountArray:
+			out[key.String()] = int64(value.Len())
+		default:
+			f.flattenSlice(out, value, key, limit)
+		}
 	case reflect.String:
 		out[key.String()] = value.String()
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -84,20 +289,216 @@ func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, sliceL
 	return nil
 }
 
-func _flattenMap(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, sliceLen int) {
+// flattenDecoded flattens the values that encoding/json decodes into
+// interface{} without reflection. It reports false if obj is not one of them.
+// Elements of other types are flattened by flatten.
+//
+// path is the escaped string of key, and depth is the length of key. key is
+// built only if the overrides need it, and is nil otherwise.
+func (f *flattener) flattenDecoded(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, path string, depth int, limit int) (bool, error) {
+	switch v := obj.(type) {
+	case nil:
+		return true, errInvalidValue
+	case string:
+		out[path] = v
+	case json.Number:
+		out[path] = v
+	case float64:
+		out[path] = v
+	case bool:
+		out[path] = v
+	case map[string]interface{}:
+		limit = f.depthLimit(key, limit)
+		if limit >= 0 && depth >= limit {
+			return true, f.writeJSON(out, v, len(v), path)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f.flattenChild(out, v[k], key, path, k, depth, limit)
+		}
+	case []interface{}:
+		limit = f.depthLimit(key, limit)
+		if limit >= 0 && depth >= limit {
+			return true, f.writeJSON(out, v, len(v), path)
+		}
+		count := len(v)
+		if f.sliceLen < count {
+			count = f.sliceLen
+		}
+		switch f.policyOf(key) {
+		case JoinArray:
+			if isPrimitiveSlice(v) {
+				if count > 0 {
+					elems := make([]string, 0, count)
+					for _, elem := range v[:count] {
+						if elem == nil {
+							elems = append(elems, "")
+						} else {
+							elems = append(elems, toString(elem))
+						}
+					}
+					out[path] = strings.Join(elems, f.arraySeparator)
+				}
+				return true, nil
+			}
+		case JSONArray:
+			return true, f.writeJSON(out, v, len(v), path)
+		case CountArray:
+			out[path] = int64(len(v))
+			return true, nil
+		}
+		for i, elem := range v[:count] {
+			f.flattenChild(out, elem, key, path, strconv.Itoa(i), depth, limit)
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// flattenChild flattens the element of the token under the key.
+// Errors of elements are ignored as flattenMap and flattenSlice do.
+func (f *flattener) flattenChild(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, path string, token string, depth int, limit int) {
+	var child jsonpointer.JSONPointer
+	if f.needsKey() {
+		child = append(key[:len(key):len(key)], jsonpointer.Token(token))
+	}
+	childPath := path + "/" + escapeToken(token)
+	if ok, _ := f.flattenDecoded(out, obj, child, childPath, depth+1, limit); !ok {
+		if child == nil {
+			child, _ = jsonpointer.New(childPath)
+		}
+		f.flatten(out, obj, child, limit)
+	}
+}
+
+// needsKey reports whether the overrides need the pointers of values.
+func (f *flattener) needsKey() bool {
+	return len(f.depthOverrides) > 0 || len(f.policyOverrides) > 0
+}
+
+// escapeToken returns the escaped token like jsonpointer.Token.EscapedString.
+func escapeToken(token string) string {
+	if !strings.ContainsAny(token, "~/") {
+		return token
+	}
+	return jsonpointer.Token(token).EscapedString()
+}
+
+// isPrimitiveSlice is isPrimitiveArray for decoded arrays.
+func isPrimitiveSlice(v []interface{}) bool {
+	for _, elem := range v {
+		switch elem.(type) {
+		case nil, string, json.Number, float64, bool:
+		case map[string]interface{}, []interface{}:
+			return false
+		default:
+			switch valueOf(elem).Kind() {
+			case reflect.Map, reflect.Slice:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *flattener) flattenMap(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, limit int) {
 	keys := sortedMapKeys(value)
 	for _, key := range keys {
 		pointer := prefix.Clone()
 		pointer.AppendString(key.String())
-		_flatten(out, value.MapIndex(key).Interface(), pointer, sliceLen)
+		f.flatten(out, value.MapIndex(key).Interface(), pointer, limit)
 	}
 }
 
-func _flattenSlice(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, sliceLen int) {
-	count := int(math.Min(float64(sliceLen), float64(value.Len())))
+func (f *flattener) flattenSlice(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, limit int) {
+	count := int(math.Min(float64(f.sliceLen), float64(value.Len())))
 	for i := 0; i < count; i++ {
 		pointer := prefix.Clone()
 		pointer.AppendString(strconv.Itoa(i))
-		_flatten(out, value.Index(i).Interface(), pointer, sliceLen)
+		f.flatten(out, value.Index(i).Interface(), pointer, limit)
+	}
+}
+
+// flattenJoinedSlice writes elements joined with the separator.
+func (f *flattener) flattenJoinedSlice(out map[string]interface{}, value reflect.Value, key jsonpointer.JSONPointer) {
+	count := int(math.Min(float64(f.sliceLen), float64(value.Len())))
+	if count == 0 {
+		return
+	}
+	elems := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		elem := valueOf(value.Index(i))
+		if !elem.IsValid() || (elem.Kind() == reflect.Interface && elem.IsNil()) {
+			elems = append(elems, "")
+		} else {
+			elems = append(elems, toString(elem.Interface()))
+		}
+	}
+	out[key.String()] = strings.Join(elems, f.arraySeparator)
+}
+
+// flattenJSON writes the object or array as a compact JSON string.
+// Empty objects and arrays are ignored like flattenMap and flattenSlice.
+func (f *flattener) flattenJSON(out map[string]interface{}, value reflect.Value, key jsonpointer.JSONPointer) error {
+	return f.writeJSON(out, value.Interface(), value.Len(), key.String())
+}
+
+// writeJSON writes obj of n elements as a compact JSON string at the path.
+func (f *flattener) writeJSON(out map[string]interface{}, obj interface{}, n int, path string) error {
+	if n == 0 {
+		return nil
+	}
+	s, err := compactJSON(obj)
+	if err != nil {
+		return err
+	}
+	out[path] = s
+	return nil
+}
+
+// isPrimitiveArray reports whether the array contains no objects and arrays.
+func isPrimitiveArray(value reflect.Value) bool {
+	for i := 0; i < value.Len(); i++ {
+		switch valueOf(value.Index(i)).Kind() {
+		case reflect.Map, reflect.Slice:
+			return false
+		}
+	}
+	return true
+}
+
+func lessPattern(a, b jsonpointer.JSONPointer) bool {
+	if wa, wb := countWildcards(a), countWildcards(b); wa != wb {
+		return wa < wb
+	}
+	return a.String() < b.String()
+}
+
+func countWildcards(pattern jsonpointer.JSONPointer) int {
+	n := 0
+	for _, token := range pattern {
+		if token == "*" {
+			n++
+		}
+	}
+	return n
+}
+
+// matchPointer reports whether the pointer matches the pattern.
+// "*" in the pattern matches any token.
+func matchPointer(pattern, pointer jsonpointer.JSONPointer) bool {
+	if pattern.Len() != pointer.Len() {
+		return false
+	}
+	for i, token := range pattern {
+		if token != "*" && token != pointer[i] {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,116 @@
+package json2csv
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var testFlattenDecodedCases = []struct {
+	json string
+	opts FlattenOptions
+}{
+	{`{"a": 1, "b": "x", "c": true, "d": null, "e": 1.5e300, "f": {}, "g": []}`, FlattenOptions{}},
+	{`{"a": {"b": [1, {"c": [null, "x"]}]}, "a/b": {"~": 2}}`, FlattenOptions{}},
+	{`{"a": [1, 2, 3, 4], "b": [[1], [2]]}`, FlattenOptions{SliceLen: 2}},
+	{`{"a": {"b": {"c": 1}}, "p": {"x": {"y": [2]}}}`, FlattenOptions{MaxDepth: 1, MaxDepthOverrides: map[string]int{"/p": 1}}},
+	{`{"t": ["a", null, 1, true], "n": [{"x": 1}], "e": []}`, FlattenOptions{ArrayPolicy: JoinArray, ArraySeparator: "|"}},
+	{`{"t": ["a", 1], "n": [{"x": 1}], "e": []}`, FlattenOptions{ArrayPolicy: JSONArray}},
+	{`{"t": ["a", 1], "n": [{"x": [1, 2]}], "e": []}`, FlattenOptions{ArrayPolicy: CountArray, ArrayPolicyOverrides: map[string]ArrayPolicy{"/n/*/x": IndexArray}}},
+	{`[{"a": 1}, {"a": [1, 2]}]`, FlattenOptions{}},
+	{`[1, "a", {"b": 2}]`, FlattenOptions{}},
+}
+
+func TestFlattenDecoded(t *testing.T) {
+	for caseIndex, testCase := range testFlattenDecodedCases {
+		data, err := json2obj(testCase.json)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := testCase.opts
+		if opts.SliceLen == 0 {
+			opts.SliceLen = math.MaxInt
+		}
+
+		expected, expectedErr := flattenReflectOnly(data, &opts)
+		actual, err := JSON2CSVWithOptions(data, nil, &opts)
+		if (err == nil) != (expectedErr == nil) {
+			t.Errorf("%d: Expected error %v, but %v", caseIndex, expectedErr, err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, expected, actual)
+		}
+	}
+}
+
+// TestFlattenGoValues tests values which are not decoded from JSON.
+func TestFlattenGoValues(t *testing.T) {
+	data := map[string]interface{}{
+		"ints":   []int{1, 2},
+		"nested": map[string]map[string]uint8{"a": {"b": 3}},
+		"mixed":  []interface{}{map[string]int{"x": 1}, int32(-1), float32(0.5)},
+	}
+	opts := NewFlattenOptions()
+	expected, _ := flattenReflectOnly(data, opts)
+	actual, err := JSON2CSVWithOptions(data, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+// flattenReflectOnly flattens data by reflection as JSON2CSVWithOptions does.
+func flattenReflectOnly(data interface{}, opts *FlattenOptions) ([]KeyValue, error) {
+	f, err := newFlattener(opts)
+	if err != nil {
+		return nil, err
+	}
+	f.reflectOnly = true
+	v := valueOf(data)
+	if v.Kind() == reflect.Slice && isObjectArray(v) {
+		results := []KeyValue{}
+		for i := 0; i < v.Len(); i++ {
+			result, err := flatten(v.Index(i), f)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+	result, err := flatten(v, f)
+	if err != nil {
+		return nil, err
+	}
+	return []KeyValue{result}, nil
+}
+
+func BenchmarkFlatten(b *testing.B) {
+	for _, bm := range syntheticBenchmarks {
+		records := bm.Spec.Records(100)
+		for _, reflectOnly := range []bool{false, true} {
+			name := bm.Name + "/decoded"
+			if reflectOnly {
+				name = bm.Name + "/reflect"
+			}
+			b.Run(name, func(b *testing.B) {
+				f, err := newFlattener(NewFlattenOptions())
+				if err != nil {
+					b.Fatal(err)
+				}
+				f.reflectOnly = reflectOnly
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for _, record := range records {
+						if _, err := flatten(record, f); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
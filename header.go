@@ -0,0 +1,83 @@
+package json2csv
+
+import (
+	"sort"
+)
+
+// Header is a compiled CSVHeader, which is built once and used for every
+// row of the output.
+type Header struct {
+	// Keys are the keys (JSON Pointers) in the order of columns.
+	Keys []string
+
+	// Names are the formatted names of Keys.
+	Names []string
+
+	index map[string]int
+}
+
+// NewHeader compiles the header. The names are formatted with the formatter
+// and renamed with the renamer if not nil.
+func NewHeader(csvHeader CSVHeader, formatter KeyFormatter, renamer *Renamer) (*Header, error) {
+	pts, err := allPointers([]KeyValue{KeyValue(csvHeader)})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(pts)
+
+	names, err := formatHeader(pts, formatter, renamer)
+	if err != nil {
+		return nil, err
+	}
+	h := &Header{
+		Keys:  pts.Strings(),
+		Names: names,
+		index: make(map[string]int, len(pts)),
+	}
+	for i, key := range h.Keys {
+		h.index[key] = i
+	}
+	return h, nil
+}
+
+// Len returns the number of columns.
+func (h *Header) Len() int {
+	return len(h.Keys)
+}
+
+// Index returns the column index of the key.
+func (h *Header) Index(key string) (int, bool) {
+	i, ok := h.index[key]
+	return i, ok
+}
+
+// Record returns the values of the row in the order of columns.
+// Null and missing values are empty, and keys absent in the header are
+// ignored.
+func (h *Header) Record(row KeyValue, formatter ValueFormatter) []string {
+	return h.record(row, formatter, "")
+}
+
+// record is like Record, but null and missing values are nullValue.
+func (h *Header) record(row KeyValue, formatter ValueFormatter, nullValue string) []string {
+	record := make([]string, len(h.Keys))
+	if nullValue != "" {
+		for i := range record {
+			record[i] = nullValue
+		}
+	}
+	if len(row) < len(h.Keys) {
+		for key, value := range row {
+			if i, ok := h.index[key]; ok && value != nil {
+				record[i] = formatValue(formatter, value)
+			}
+		}
+		return record
+	}
+	for i, key := range h.Keys {
+		if value, ok := row[key]; ok && value != nil {
+			record[i] = formatValue(formatter, value)
+		}
+	}
+	return record
+}
@@ -0,0 +1,136 @@
+package json2csv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testHeaderRecordCases = []struct {
+	row      KeyValue
+	expected []string
+}{
+	{KeyValue{"/a": 1, "/b/0": "x", "/b/10": true}, []string{"1", "x", "true"}},
+	{KeyValue{"/b/10": 2}, []string{"", "", "2"}},
+	{KeyValue{"/a": 1, "/c": 3, "/d": 4, "/e": 5}, []string{"1", "", ""}},
+	{KeyValue{}, []string{"", "", ""}},
+	{KeyValue{"/a": nil, "/b/0": "x"}, []string{"", "x", ""}},
+	{KeyValue{"/a": nil, "/b/0": nil, "/b/10": nil, "/c": nil}, []string{"", "", ""}},
+}
+
+func TestHeader(t *testing.T) {
+	header, err := NewHeader(CSVHeader{"/b/10": "", "/a": "", "/b/0": ""}, DotBracketStyle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/a", "/b/0", "/b/10"}; !reflect.DeepEqual(header.Keys, expected) {
+		t.Errorf("Expected %v, but %v", expected, header.Keys)
+	}
+	if expected := []string{"a", "b[0]", "b[10]"}; !reflect.DeepEqual(header.Names, expected) {
+		t.Errorf("Expected %v, but %v", expected, header.Names)
+	}
+	if i, ok := header.Index("/b/10"); !ok || i != 2 {
+		t.Errorf("Expected %v, but %v", 2, i)
+	}
+
+	for caseIndex, testCase := range testHeaderRecordCases {
+		actual := header.Record(testCase.row, nil)
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestWriteCSVByHeaderKeepsRows(t *testing.T) {
+	var b bytes.Buffer
+	w := NewCSVWriter(&b, JSONPointerStyle, false)
+	rows := []KeyValue{{"/a": 1}, {"/b": 2}}
+	if err := w.WriteCSVByHeader(rows, CSVHeader{"/a": "", "/b": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1,\n,2\n"; b.String() != expected {
+		t.Errorf("Expected %q, but %q", expected, b.String())
+	}
+	if expected := []KeyValue{{"/a": 1}, {"/b": 2}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, but %v", expected, rows)
+	}
+}
+
+func TestNullValue(t *testing.T) {
+	rows := []KeyValue{{"/a": 1, "/b": nil}, {"/b": "x"}}
+	for transpose, expected := range map[bool]string{
+		false: "/a,/b\n1,NULL\nNULL,x\n",
+		true:  "/a,1,NULL\n/b,NULL,x\n",
+	} {
+		var b bytes.Buffer
+		w := NewCSVWriter(&b, JSONPointerStyle, transpose)
+		w.NullValue = "NULL"
+		if err := w.WriteCSV(rows); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Errorf("transpose=%v: Expected %q, but %q", transpose, expected, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+	w := NewCSVWriter(&b, JSONPointerStyle, false)
+	w.NullValue = "NULL"
+	if err := w.WriteCSVByHeader(rows, CSVHeader{"/a": "", "/b": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1,NULL\nNULL,x\n"; b.String() != expected {
+		t.Errorf("Expected %q, but %q", expected, b.String())
+	}
+}
+
+// benchmarkWideRows returns n sparse rows of a header of width columns.
+func benchmarkWideRows(n, width int) (CSVHeader, []KeyValue) {
+	csvHeader := CSVHeader{}
+	for i := 0; i < width; i++ {
+		csvHeader[fmt.Sprintf("/col/%d", i)] = ""
+	}
+	rows := make([]KeyValue, 0, n)
+	for i := 0; i < n; i++ {
+		row := KeyValue{}
+		for j := i % 10; j < width; j += 10 {
+			row[fmt.Sprintf("/col/%d", j)] = i
+		}
+		rows = append(rows, row)
+	}
+	return csvHeader, rows
+}
+
+func BenchmarkWriteCSVByHeader(b *testing.B) {
+	csvHeader, rows := benchmarkWideRows(100, 500)
+	w := NewCSVWriter(&bytes.Buffer{}, JSONPointerStyle, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, row := range rows {
+			if err := w.WriteCSVByHeader([]KeyValue{row}, csvHeader); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteRows(b *testing.B) {
+	csvHeader, rows := benchmarkWideRows(100, 500)
+	w := NewCSVWriter(&bytes.Buffer{}, JSONPointerStyle, false)
+	header, err := w.CompileHeader(csvHeader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, row := range rows {
+			if err := w.WriteRows([]KeyValue{row}, header); err != nil {
+				b.Fatal(err)
+			}
+		}
+		w.Flush()
+	}
+}
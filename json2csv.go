@@ -2,6 +2,7 @@
 package json2csv
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/yukithm/json2csv/jsonpointer"
@@ -21,76 +22,92 @@ type CSVHeader map[string]interface{}
 // JSON2CSV converts JSON to CSV.
 // Update CSVHeader according to the data provided if csvHeader is not nil
 func JSON2CSV(data interface{}, csvHeader CSVHeader, sliceLen int) ([]KeyValue, error) {
+	return JSON2CSVWithOptions(data, csvHeader, &FlattenOptions{SliceLen: sliceLen})
+}
+
+// JSON2CSVWithOptions is like JSON2CSV but flattens with the given options.
+func JSON2CSVWithOptions(data interface{}, csvHeader CSVHeader, opts *FlattenOptions) ([]KeyValue, error) {
+	f, err := newFlattener(opts)
+	if err != nil {
+		return nil, err
+	}
+	results, err := flattenData(data, f)
+	if err != nil {
+		return nil, err
+	}
+	if csvHeader != nil {
+		for _, result := range results {
+			for s := range result {
+				csvHeader[s] = ""
+			}
+		}
+	}
+	return results, nil
+}
+
+// flattenData flattens the decoded JSON data into rows with the flattener.
+func flattenData(data interface{}, f *flattener) ([]KeyValue, error) {
+	var err error
 	results := []KeyValue{}
 	v := valueOf(data)
 	switch v.Kind() {
 	case reflect.Map:
 		if v.Len() > 0 {
-			result, err := flatten(v, sliceLen)
+			if ok, err := f.match(v); err != nil {
+				return nil, err
+			} else if !ok {
+				return results, nil
+			}
+			result, err := flatten(v, f)
 			if err != nil {
 				return nil, err
 			}
 			results = append(results, result)
-			if csvHeader != nil {
-				for s := range result {
-					csvHeader[s] = ""
-				}
-			}
 		}
 	case reflect.Slice:
-		count := int(math.Min(float64(sliceLen), float64(v.Len())))
+		count := int(math.Min(float64(f.sliceLen), float64(v.Len())))
 		if isObjectArray(v) {
 			for i := 0; i < count; i++ {
-				result, err := flatten(v.Index(i), sliceLen)
+				if ok, err := f.match(valueOf(v.Index(i))); err != nil {
+					return nil, err
+				} else if !ok {
+					continue
+				}
+				result, err := flatten(v.Index(i), f)
 				if err != nil {
 					return nil, err
 				}
 				results = append(results, result)
-				if csvHeader != nil {
-					for s := range result {
-						csvHeader[s] = ""
-					}
-				}
 			}
 		} else if v.Len() > 0 {
-			result, err := flatten(v, sliceLen)
+			if ok, err := f.match(v); err != nil {
+				return nil, err
+			} else if !ok {
+				return results, nil
+			}
+			result, err := flatten(v, f)
 			if err != nil {
 				return nil, err
 			}
 			if result != nil {
 				results = append(results, result)
-				if csvHeader != nil {
-					for s := range result {
-						csvHeader[s] = ""
-					}
-				}
 			}
 		}
 	default:
 		return nil, errors.New("Unsupported JSON structure.")
 	}
 
+	if f.melt != nil {
+		results, err = f.melt.Apply(results)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return results, nil
 }
 
 func JSON2CSVHeader(reader JSONStreamReader, path string, sliceLen int) (CSVHeader, error) {
-	header := CSVHeader{}
-	var data interface{}
-	var err error
-	for reader.HasNext() {
-		data = reader.Read()
-		if path != "" {
-			data, err = jsonpointer.Get(data, path)
-			if err != nil {
-				return header, err
-			}
-		}
-		_, err := JSON2CSV(data, header, sliceLen)
-		if err != nil {
-			return header, err
-		}
-	}
-	return header, nil
+	return streamConverter(path, &FlattenOptions{SliceLen: sliceLen}).Header(context.Background(), reader)
 }
 
 // FormatCSVHeaderToDotBracket convert given JSONPointerStyle header to DotBracketStyle.
@@ -106,33 +123,50 @@ func FormatCSVHeaderToDotBracket(header string) (string, error) {
 }
 
 func JSON2CSVOnline(reader JSONStreamReader, csvHeader CSVHeader, output io.Writer, style KeyStyle, transpose bool, path string, sliceLen int) error {
-	writer := NewCSVWriter(output, style, transpose)
-	err := writer.WriterHeader(csvHeader)
-	if err != nil {
-		return err
+	c := &Converter{opts: Options{Path: path, Flatten: &FlattenOptions{SliceLen: sliceLen}, KeyStyle: style, Transpose: transpose}}
+	return c.ConvertStream(context.Background(), reader, csvHeader, output)
+}
+
+// JSON2ColumnTypes scans the stream and returns the type of each column.
+// The keys of the result are the same as JSON2CSVHeader.
+func JSON2ColumnTypes(reader JSONStreamReader, path string, opts *FlattenOptions) (ColumnTypes, error) {
+	return streamConverter(path, opts).ColumnTypes(context.Background(), reader)
+}
+
+// JSON2ArrowOnline converts the stream to Apache Arrow IPC, writing a record
+// batch for every batchSize rows.
+func JSON2ArrowOnline(reader JSONStreamReader, types ColumnTypes, output io.Writer, style KeyStyle, format ArrowFormat, path string, opts *FlattenOptions, batchSize int) error {
+	c := &Converter{opts: Options{Path: path, Flatten: opts, KeyStyle: style}}
+	return c.ConvertArrowStream(context.Background(), reader, types, output, format, batchSize)
+}
+
+// readRows reads the next record of the reader and flattens it with the
+// flattener, which is created once for the stream.
+// Records of JSONStreamRawReader are flattened by their tokens without
+// decoding them if possible.
+func readRows(reader JSONStreamReader, path string, f *flattener) ([]KeyValue, error) {
+	if raw, ok := reader.(JSONStreamRawReader); ok && canFlattenTokens(path, f) {
+		return flattenTokens(raw.ReadRaw(), f)
 	}
-	var data interface{}
-	for reader.HasNext() {
-		data = reader.Read()
-		if path != "" {
-			data, err = jsonpointer.Get(data, path)
-			if err != nil {
-				return err
-			}
-		}
-		csvRow, err := JSON2CSV(data, nil, sliceLen)
-		if err != nil {
-			return err
-		}
-		err = writer.WriteCSVByHeader(
-			csvRow,
-			csvHeader,
-		)
+	return flattenRecord(reader.Read(), path, f)
+}
+
+// flattenRecord flattens the decoded record of a stream at the path.
+func flattenRecord(data interface{}, path string, f *flattener) ([]KeyValue, error) {
+	if path != "" {
+		var err error
+		data, err = jsonpointer.Get(data, path)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return flattenData(data, f)
+}
+
+// canFlattenTokens reports whether flattenTokens can flatten records of the
+// stream, which needs the whole record at the path or by the filter.
+func canFlattenTokens(path string, f *flattener) bool {
+	return path == "" && f.filter == nil
 }
 
 func isObjectArray(obj interface{}) bool {
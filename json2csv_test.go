@@ -3,9 +3,13 @@ package json2csv
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -139,6 +143,36 @@ func TestJSON2CSV(t *testing.T) {
 	}
 }
 
+var testStreamJSON = []string{
+	`{"id": 1, "name": "foo", "score": 1.5, "active": true}`,
+	`{"id": 2, "name": "bar", "tags": ["a", "b"]}`,
+	`{"id": 3, "score": 2, "active": false}`,
+}
+
+// createTestZip creates a zip file which contains each JSON as a file.
+func createTestZip(t *testing.T, jsons []string) string {
+	filename := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for i, j := range jsons {
+		w, err := zw.Create(fmt.Sprintf("%d.json", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(j)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
 func TestJSON2CSVOnline(t *testing.T) {
 	// extract csvHeader
 	zipReader, err := zip.OpenReader("test.zip")
@@ -164,3 +198,299 @@ func TestJSON2CSVOnline(t *testing.T) {
 		t.Errorf("ExceptionL %v", err)
 	}
 }
+
+var testJSON2CSVWithOptionsCases = []struct {
+	json     string
+	opts     FlattenOptions
+	expected []KeyValue
+}{
+	{
+		`{"a": {"b": {"c": 1, "d": [1, 2]}}, "e": {}}`,
+		FlattenOptions{SliceLen: math.MaxInt, MaxDepth: 1},
+		[]KeyValue{{"/a": `{"b":{"c":1,"d":[1,2]}}`}},
+	},
+	{
+		`{"a": {"b": {"c": 1, "d": [1, 2]}}, "e": {}}`,
+		FlattenOptions{SliceLen: math.MaxInt, MaxDepth: 3},
+		[]KeyValue{{"/a/b/c": json.Number("1"), "/a/b/d": `[1,2]`}},
+	},
+	{
+		`[{"id": 1, "p": {"x": {"y": "<z>"}}}]`,
+		FlattenOptions{SliceLen: math.MaxInt, MaxDepthOverrides: map[string]int{"/p": 0}},
+		[]KeyValue{{"/id": json.Number("1"), "/p": `{"x":{"y":"<z>"}}`}},
+	},
+	{
+		`{"items": [{"p": {"x": 1}}, {"p": {"y": 2}}], "p": {"z": 3}}`,
+		FlattenOptions{SliceLen: math.MaxInt, MaxDepthOverrides: map[string]int{"/items/*/p": 0}},
+		[]KeyValue{{"/items/0/p": `{"x":1}`, "/items/1/p": `{"y":2}`, "/p/z": json.Number("3")}},
+	},
+	{
+		`{"a": {"b": {"c": 1}}, "p": {"x": {"y": 2}}}`,
+		FlattenOptions{SliceLen: math.MaxInt, MaxDepth: 1, MaxDepthOverrides: map[string]int{"/p": 1}},
+		[]KeyValue{{"/a": `{"b":{"c":1}}`, "/p/x": `{"y":2}`}},
+	},
+}
+
+func TestJSON2CSVWithOptions(t *testing.T) {
+	for caseIndex, testCase := range testJSON2CSVWithOptionsCases {
+		obj, err := json2obj(testCase.json)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := JSON2CSVWithOptions(obj, nil, &testCase.opts)
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+		} else if !reflect.DeepEqual(testCase.expected, actual) {
+			t.Errorf("%d: Expected %#v, but %#v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+var testArrayPolicyCases = []struct {
+	opts     FlattenOptions
+	expected KeyValue
+}{
+	{
+		FlattenOptions{SliceLen: math.MaxInt},
+		KeyValue{"/tags/0": "a", "/tags/1": "b", "/tags/2": "c", "/items/0/id": json.Number("1"), "/mixed/0": json.Number("1"), "/mixed/2": "x"},
+	},
+	{
+		FlattenOptions{SliceLen: math.MaxInt, ArrayPolicy: JoinArray, ArraySeparator: "|"},
+		KeyValue{"/tags": "a|b|c", "/items/0/id": json.Number("1"), "/mixed": "1||x"},
+	},
+	{
+		FlattenOptions{SliceLen: 2, ArrayPolicy: JoinArray},
+		KeyValue{"/tags": "a,b", "/items/0/id": json.Number("1"), "/mixed": "1,"},
+	},
+	{
+		FlattenOptions{SliceLen: math.MaxInt, ArrayPolicy: JSONArray},
+		KeyValue{"/tags": `["a","b","c"]`, "/items": `[{"id":1}]`, "/mixed": `[1,null,"x"]`},
+	},
+	{
+		FlattenOptions{SliceLen: math.MaxInt, ArrayPolicy: CountArray},
+		KeyValue{"/tags": int64(3), "/items": int64(1), "/mixed": int64(3), "/empty": int64(0)},
+	},
+	{
+		FlattenOptions{SliceLen: math.MaxInt, ArrayPolicyOverrides: map[string]ArrayPolicy{"/tags": JoinArray, "/*": CountArray}},
+		KeyValue{"/tags": "a,b,c", "/items": int64(1), "/mixed": int64(3), "/empty": int64(0)},
+	},
+}
+
+func TestArrayPolicy(t *testing.T) {
+	obj, err := json2obj(`{"tags": ["a", "b", "c"], "items": [{"id": 1}], "mixed": [1, null, "x"], "empty": []}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for caseIndex, testCase := range testArrayPolicyCases {
+		actual, err := JSON2CSVWithOptions(obj, nil, &testCase.opts)
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+		} else if !reflect.DeepEqual([]KeyValue{testCase.expected}, actual) {
+			t.Errorf("%d: Expected %#v, but %#v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := NewExprFilter(`/active && /score >= 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := NewFlattenOptions()
+	opts.Filter = filter
+
+	obj, err := json2obj(`[
+		{"id": 1, "active": true, "score": 1},
+		{"id": 2, "active": true, "score": 2},
+		{"id": 3, "active": false, "score": 3}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := JSON2CSVWithOptions(obj, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeyValue{{"/id": json.Number("2"), "/active": true, "/score": json.Number("2")}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	// stream
+	zipReader, err := zip.OpenReader(createTestZip(t, testStreamJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zipReader.Close()
+	opts.Filter, err = NewExprFilter(`exists(/tags)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestConverter(t, Options{Flatten: opts})
+	csvHeader, err := c.Header(context.Background(), NewJSONStreamZipReader(zipReader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	err = c.ConvertStream(context.Background(), NewJSONStreamZipReader(zipReader), csvHeader, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/id,/name,/tags/0,/tags/1\n2,bar,a,b\n"
+	if got := b.String(); got != want {
+		t.Errorf("Expected %q, but %q", want, got)
+	}
+}
+
+func TestComputedColumns(t *testing.T) {
+	opts := NewFlattenOptions()
+	for _, def := range []string{
+		`total = /items/0/price * /items/0/qty`,
+		`/name/full=concat(/first, " ", coalesce(/last, "-"))`,
+		`expensive=/total >= 10`,
+		`missing=/nothing + 1`,
+	} {
+		column, err := ParseComputedColumn(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Columns = append(opts.Columns, column)
+	}
+
+	obj, err := json2obj(`[
+		{"first": "Ann", "last": "Lee", "items": [{"price": 2.5, "qty": 4}]},
+		{"first": "Bo", "items": [{"price": 1, "qty": 3}]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvHeader := CSVHeader{}
+	actual, err := JSON2CSVWithOptions(obj, csvHeader, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeyValue{
+		{
+			"/first": "Ann", "/last": "Lee", "/items/0/price": json.Number("2.5"), "/items/0/qty": json.Number("4"),
+			"/total": 10.0, "/name/full": "Ann Lee", "/expensive": true,
+		},
+		{
+			"/first": "Bo", "/items/0/price": json.Number("1"), "/items/0/qty": json.Number("3"),
+			"/total": int64(3), "/name/full": "Bo -", "/expensive": false,
+		},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+	if _, ok := csvHeader["/total"]; !ok {
+		t.Errorf("Expected /total in header, but %v", csvHeader)
+	}
+
+	for _, def := range []string{`total`, `=1`, `total=/a +`} {
+		if _, err := ParseComputedColumn(def); err == nil {
+			t.Errorf("Expected error for %q", def)
+		}
+	}
+
+	// existing columns are not replaced
+	for _, def := range []string{`first=concat(/first, "!")`, `last=/nothing`} {
+		column, err := ParseComputedColumn(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := &FlattenOptions{SliceLen: math.MaxInt, Columns: []*ComputedColumn{column}}
+		if _, err := JSON2CSVWithOptions(obj, nil, opts); err == nil {
+			t.Errorf("Expected error for %q", def)
+		}
+	}
+}
+
+func BenchmarkJSON2CSV(b *testing.B) {
+	for _, bm := range syntheticBenchmarks {
+		records := bm.Spec.Records(100)
+		b.Run(bm.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := JSON2CSV(records, CSVHeader{}, math.MaxInt); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkJSON2CSVOnline(b *testing.B) {
+	spec := SyntheticSpec{Width: 30, Depth: 2, ArrayLen: 2, Sparsity: 0.2}
+	filename := filepath.Join(b.TempDir(), "bench.jsonl")
+	f, err := os.Create(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := spec.WriteLines(f, 1000); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	open := func() JSONStreamReader {
+		f, err := os.Open(filename)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return NewJSONStreamLineReader(f)
+	}
+	reader := open()
+	csvHeader, err := newTestConverter(b, Options{}).Header(context.Background(), reader)
+	reader.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		c := newTestConverter(b, Options{Workers: workers})
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				reader := open()
+				err := c.ConvertStream(context.Background(), reader, csvHeader, io.Discard)
+				reader.Close()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestAllocationBudget fails if converting a record allocates much more than
+// it does now. Raise the budget only with a reason.
+func TestAllocationBudget(t *testing.T) {
+	spec := SyntheticSpec{Width: 7, Depth: 2, ArrayLen: 2}
+	record := spec.Record(0)
+	opts := NewFlattenOptions()
+	rows, err := JSON2CSVWithOptions(record, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaves := len(rows[0])
+
+	// a key and a boxed value for each leaf, and pointers of nested values
+	budget := float64(3*leaves + 10)
+	allocs := testing.AllocsPerRun(100, func() {
+		JSON2CSVWithOptions(record, nil, opts)
+	})
+	if allocs > budget {
+		t.Errorf("JSON2CSVWithOptions: Expected at most %v allocations for %d leaves, but %v", budget, leaves, allocs)
+	}
+
+	// only the record itself
+	header, err := NewHeader(CSVHeader(rows[0]), JSONPointerStyle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		header.Record(rows[0], nil)
+	})
+	if allocs > 1 {
+		t.Errorf("Header.Record: Expected at most %v allocations, but %v", 1, allocs)
+	}
+}
@@ -1,7 +1,6 @@
 package json2csv
 
 import (
-	"encoding/json"
 	"bufio"
 	"os"
 	"log"
@@ -22,6 +21,7 @@ type JSONStreamLineReader struct {
 	f *os.File
 	scanner *bufio.Scanner
 	end bool
+	bytes int64
 }
 
 func (jr *JSONStreamLineReader) HasNext() bool {
@@ -34,10 +34,27 @@ func (jr *JSONStreamLineReader) Close() {
 
 func (jr *JSONStreamLineReader) Read() map[string]interface{} {
 	res := make(map[string]interface{})
-	_ = json.Unmarshal(jr.scanner.Bytes(), &res)
+	_ = decodeJSONObject(jr.scanner.Bytes(), &res)
+	jr.bytes += int64(len(jr.scanner.Bytes())) + 1
 	jr.end = !jr.scanner.Scan()
 	if jr.end && jr.scanner.Err() != nil{
 		log.Println(jr.scanner.Err())
 	}
 	return res
 }
+
+// ReadRaw returns the next line without decoding it.
+func (jr *JSONStreamLineReader) ReadRaw() []byte {
+	res := append([]byte(nil), jr.scanner.Bytes()...)
+	jr.bytes += int64(len(res)) + 1
+	jr.end = !jr.scanner.Scan()
+	if jr.end && jr.scanner.Err() != nil {
+		log.Println(jr.scanner.Err())
+	}
+	return res
+}
+
+// BytesRead returns the bytes of lines read, including newlines.
+func (jr *JSONStreamLineReader) BytesRead() int64 {
+	return jr.bytes
+}
@@ -0,0 +1,34 @@
+package json2csv
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONStreamLineReaderNumbers(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.jsonl")
+	if err := os.WriteFile(filename, []byte(`{"a": 1.0, "b": 12345678901234567890}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewJSONStreamLineReader(file)
+	defer reader.Close()
+
+	results, err := JSON2CSV(reader.Read(), nil, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := NewCSVWriter(&b, JSONPointerStyle, false).WriteCSV(results); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/a,/b\n1.0,12345678901234567890\n"; b.String() != expected {
+		t.Errorf("Expected %q, but %q", expected, b.String())
+	}
+}
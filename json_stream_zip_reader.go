@@ -2,7 +2,6 @@ package json2csv
 
 import (
 	"archive/zip"
-	"encoding/json"
 	"io"
 )
 
@@ -17,6 +16,7 @@ type JSONStreamZipReader struct {
 	data  []*zip.File
     reader  *zip.ReadCloser
 	index int
+	bytes int64
 }
 
 func (jz *JSONStreamZipReader) HasNext() bool {
@@ -30,10 +30,41 @@ func (jz *JSONStreamZipReader) Close() {
 func (jz *JSONStreamZipReader) Read() map[string]interface{} {
 	child := jz.data[jz.index]
 	jz.index++
+	jz.bytes += int64(child.CompressedSize64)
 	res := make(map[string]interface{})
 	cfd, _ := child.Open()
 	content, _ := io.ReadAll(cfd)
-	_ = json.Unmarshal(content, &res)
+	_ = decodeJSONObject(content, &res)
 	_ = cfd.Close()
 	return res
 }
+
+// ReadRaw returns the content of the next file without decoding it.
+func (jz *JSONStreamZipReader) ReadRaw() []byte {
+	child := jz.data[jz.index]
+	jz.index++
+	jz.bytes += int64(child.CompressedSize64)
+	cfd, err := child.Open()
+	if err != nil {
+		return nil
+	}
+	content, _ := io.ReadAll(cfd)
+	_ = cfd.Close()
+	return content
+}
+
+// BytesRead returns the compressed size of files read, which approximates
+// the bytes read from the zip file.
+func (jz *JSONStreamZipReader) BytesRead() int64 {
+	return jz.bytes
+}
+
+// BytesTotal returns the compressed size of all files, which BytesRead
+// reaches when all files are read.
+func (jz *JSONStreamZipReader) BytesTotal() int64 {
+	var total int64
+	for _, child := range jz.data {
+		total += int64(child.CompressedSize64)
+	}
+	return total
+}
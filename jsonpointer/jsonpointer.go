@@ -108,7 +108,7 @@ func (p JSONPointer) String() string {
 		return ""
 	}
 
-	return "/" + strings.Join(p.EscapedStrings(), "/")
+	return "/" + strings.Join(s, "/")
 }
 
 // DotNotation returns dot-notated representation.
@@ -157,6 +157,41 @@ func (p JSONPointer) Get(obj interface{}) (value interface{}, err error) {
 	return v.Interface(), nil
 }
 
+// Lookup retrieves a value from the obj and reports whether it exists.
+// Unlike Get, a missing value is not an error.
+func (p JSONPointer) Lookup(obj interface{}) (interface{}, bool) {
+	v := valueOf(obj)
+	for _, token := range p {
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			v = v.MapIndex(reflect.ValueOf(string(token)).Convert(v.Type().Key()))
+		case reflect.Slice, reflect.Array:
+			if !token.IsIndex() {
+				return nil, false
+			}
+			index, _ := strconv.Atoi(string(token))
+			if index >= v.Len() {
+				return nil, false
+			}
+			v = v.Index(index)
+		default:
+			return nil, false
+		}
+		if !v.IsValid() {
+			return nil, false
+		}
+		v = valueOf(v)
+	}
+
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return nil, v.IsValid()
+	}
+	return v.Interface(), true
+}
+
 func valueOf(obj interface{}) reflect.Value {
 	v, ok := obj.(reflect.Value)
 	if !ok {
@@ -340,3 +340,40 @@ func TestGet(t *testing.T) {
 		t.Errorf("Expected %v, but %v", obj, actual)
 	}
 }
+
+var testLookupCases = []struct {
+	pointer  string
+	expected interface{}
+	ok       bool
+}{
+	{`/foo/bar/0`, 10.0, true},
+	{`/foo/bar/1/baz`, 123.0, true},
+	{`/foo/bar/2`, nil, false},
+	{`/foo/bar/-1`, nil, false},
+	{`/foo/baz`, nil, false},
+	{`/foo~1bar`, 1.23, true},
+	{`/bar`, true, true},
+	{`/bar/baz`, nil, false},
+	{`/baz`, nil, true},
+	{`/baz/qux`, nil, false},
+	{`/boo`, nil, false},
+	{`/ foo / bar `, 456.0, true},
+}
+
+func TestLookup(t *testing.T) {
+	var obj interface{}
+	if err := json.Unmarshal([]byte(testGetJSON), &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	for caseIndex, testCase := range testLookupCases {
+		pointer, err := New(testCase.pointer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, ok := pointer.Lookup(obj)
+		if ok != testCase.ok || !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, %v, but %v, %v", caseIndex, testCase.expected, testCase.ok, actual, ok)
+		}
+	}
+}
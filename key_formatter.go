@@ -0,0 +1,216 @@
+package json2csv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// KeyFormatter converts between JSON Pointers and header keys.
+//
+// ParseKey is the reverse of FormatKey. Some formats are lossy, e.g. a key
+// containing the separator, so ParseKey may not restore the original pointer.
+type KeyFormatter interface {
+	FormatKey(pointer jsonpointer.JSONPointer) string
+	ParseKey(key string) (jsonpointer.JSONPointer, error)
+}
+
+// FormatKey returns the key represented in the style.
+func (s KeyStyle) FormatKey(pointer jsonpointer.JSONPointer) string {
+	switch s {
+	case SlashStyle:
+		return strings.Join(pointer.Strings(), "/")
+	case DotNotationStyle:
+		return pointer.DotNotation(false)
+	case DotBracketStyle:
+		return pointer.DotNotation(true)
+	case SnakeCaseStyle:
+		return strings.Join(pointer.Strings(), "_")
+	case CamelCaseStyle:
+		tokens := pointer.Strings()
+		for i := 1; i < len(tokens); i++ {
+			tokens[i] = upperFirst(tokens[i])
+		}
+		return strings.Join(tokens, "")
+	default:
+		return pointer.String()
+	}
+}
+
+// ParseKey parses the key represented in the style.
+func (s KeyStyle) ParseKey(key string) (jsonpointer.JSONPointer, error) {
+	if key == "" {
+		return jsonpointer.JSONPointer{}, nil
+	}
+
+	switch s {
+	case SlashStyle:
+		return tokensToPointer(strings.Split(key, "/")), nil
+	case DotNotationStyle:
+		return tokensToPointer(strings.Split(key, ".")), nil
+	case DotBracketStyle:
+		return parseDotBracket(key)
+	case SnakeCaseStyle:
+		return tokensToPointer(strings.Split(key, "_")), nil
+	case CamelCaseStyle:
+		return tokensToPointer(splitCamelCase(key)), nil
+	default:
+		return jsonpointer.New(key)
+	}
+}
+
+// TemplateKeyFormatter formats keys with text/template.
+// The template is executed with the tokens of the pointer ([]string), and
+// "join" function (strings.Join) is available, e.g. `{{join . "__"}}`.
+type TemplateKeyFormatter struct {
+	text string
+	tmpl *template.Template
+
+	// prefix, separator and suffix to parse keys.
+	// They are detected from the output of sample tokens.
+	prefix    string
+	separator string
+	suffix    string
+	parsable  bool
+}
+
+var keyTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// NewTemplateKeyFormatter returns new TemplateKeyFormatter with the template text.
+func NewTemplateKeyFormatter(text string) (*TemplateKeyFormatter, error) {
+	tmpl, err := template.New("key").Funcs(keyTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	f := &TemplateKeyFormatter{text: text, tmpl: tmpl}
+
+	// Detect the layout by formatting placeholder tokens.
+	// Templates that don't simply join the tokens can't be parsed.
+	one, err1 := f.execute([]string{"\x00"})
+	two, err2 := f.execute([]string{"\x00", "\x01"})
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("Invalid key template %q: %w", text, errors.Join(err1, err2))
+	}
+	if i := strings.Index(one, "\x00"); i >= 0 && strings.Count(one, "\x00") == 1 {
+		f.prefix, f.suffix = one[:i], one[i+1:]
+		if strings.HasPrefix(two, f.prefix+"\x00") && strings.HasSuffix(two, "\x01"+f.suffix) {
+			f.separator = strings.TrimSuffix(strings.TrimPrefix(two, f.prefix+"\x00"), "\x01"+f.suffix)
+			f.parsable = f.separator != "" && !strings.ContainsAny(f.separator, "\x00\x01")
+		}
+	}
+	return f, nil
+}
+
+// FormatKey returns the key generated by the template.
+// It returns the JSON Pointer representation if the template fails.
+func (f *TemplateKeyFormatter) FormatKey(pointer jsonpointer.JSONPointer) string {
+	key, err := f.execute(pointer.Strings())
+	if err != nil {
+		return pointer.String()
+	}
+	return key
+}
+
+// ParseKey parses the key generated by the template.
+// Only templates that join the tokens with a separator can be parsed.
+func (f *TemplateKeyFormatter) ParseKey(key string) (jsonpointer.JSONPointer, error) {
+	if !f.parsable {
+		return nil, fmt.Errorf("Cannot parse keys of the template %q", f.text)
+	}
+	if !strings.HasPrefix(key, f.prefix) || !strings.HasSuffix(key, f.suffix) || len(key) < len(f.prefix)+len(f.suffix) {
+		return nil, fmt.Errorf("Invalid key %q", key)
+	}
+	key = key[len(f.prefix) : len(key)-len(f.suffix)]
+	return tokensToPointer(strings.Split(key, f.separator)), nil
+}
+
+func (f *TemplateKeyFormatter) execute(tokens []string) (string, error) {
+	var b bytes.Buffer
+	if err := f.tmpl.Execute(&b, tokens); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func tokensToPointer(tokens []string) jsonpointer.JSONPointer {
+	pointer := make(jsonpointer.JSONPointer, 0, len(tokens))
+	for _, token := range tokens {
+		pointer = append(pointer, jsonpointer.Token(token))
+	}
+	return pointer
+}
+
+// parseDotBracket parses "foo.bar[0].baz" style key.
+func parseDotBracket(key string) (jsonpointer.JSONPointer, error) {
+	tokens := []string{}
+	for _, part := range strings.Split(key, ".") {
+		name := part
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+		}
+		if name != "" || !strings.HasPrefix(part, "[") {
+			tokens = append(tokens, name)
+		}
+		for rest := part[len(name):]; rest != ""; {
+			end := strings.Index(rest, "]")
+			if !strings.HasPrefix(rest, "[") || end < 0 {
+				return nil, fmt.Errorf("Invalid dot-bracket key %q", key)
+			}
+			tokens = append(tokens, rest[1:end])
+			rest = rest[end+1:]
+		}
+	}
+	return tokensToPointer(tokens), nil
+}
+
+// splitCamelCase splits "fooBar0Baz" into "foo", "bar", "0" and "baz".
+func splitCamelCase(key string) []string {
+	tokens := []string{}
+	start := 0
+	prev := rune(-1)
+	for i, r := range key {
+		if i > start && (unicode.IsUpper(r) || unicode.IsDigit(r) != unicode.IsDigit(prev)) {
+			tokens = append(tokens, key[start:i])
+			start = i
+		}
+		prev = r
+	}
+	tokens = append(tokens, key[start:])
+	// The first token is not capitalized by FormatKey.
+	for i := 1; i < len(tokens); i++ {
+		tokens[i] = lowerFirst(tokens[i])
+	}
+	return tokens
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// keyFormatterOf returns formatter if not nil, otherwise style.
+func keyFormatterOf(style KeyStyle, formatter KeyFormatter) KeyFormatter {
+	if formatter != nil {
+		return formatter
+	}
+	return style
+}
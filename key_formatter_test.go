@@ -0,0 +1,98 @@
+package json2csv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+var testKeyStyleCases = []struct {
+	style    KeyStyle
+	pointer  string
+	expected string
+}{
+	{JSONPointerStyle, "/foo/bar/0/baz", "/foo/bar/0/baz"},
+	{JSONPointerStyle, "/a~1b/c~0d", "/a~1b/c~0d"},
+	{SlashStyle, "/foo/bar/0/baz", "foo/bar/0/baz"},
+	{DotNotationStyle, "/foo/bar/0/baz", "foo.bar.0.baz"},
+	{DotBracketStyle, "/foo/bar/0/baz", "foo.bar[0].baz"},
+	{DotBracketStyle, "/foo/0/1/baz", "foo[0][1].baz"},
+	{SnakeCaseStyle, "/foo/bar/0/baz", "foo_bar_0_baz"},
+	{CamelCaseStyle, "/foo/bar/0/baz", "fooBar0Baz"},
+	{CamelCaseStyle, "/Foo/bar/10/baz", "FooBar10Baz"},
+}
+
+func TestKeyStyle(t *testing.T) {
+	for caseIndex, testCase := range testKeyStyleCases {
+		pointer, err := jsonpointer.New(testCase.pointer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := testCase.style.FormatKey(pointer)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, actual)
+			continue
+		}
+
+		parsed, err := testCase.style.ParseKey(actual)
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, pointer) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, pointer, parsed)
+		}
+	}
+}
+
+var testTemplateKeyFormatterCases = []struct {
+	template string
+	expected string
+	parsable bool
+}{
+	{`{{join . "__"}}`, "foo__bar__0__baz", true},
+	{`col[{{join . "."}}]`, "col[foo.bar.0.baz]", true},
+	{`{{range .}}<{{.}}>{{end}}`, "<foo><bar><0><baz>", true},
+	{`{{len .}}`, "4", false},
+	{`{{index . 0}}`, "foo", false},
+}
+
+func TestTemplateKeyFormatter(t *testing.T) {
+	pointer, _ := jsonpointer.New("/foo/bar/0/baz")
+	for caseIndex, testCase := range testTemplateKeyFormatterCases {
+		f, err := NewTemplateKeyFormatter(testCase.template)
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		actual := f.FormatKey(pointer)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, actual)
+			continue
+		}
+
+		parsed, err := f.ParseKey(actual)
+		if !testCase.parsable {
+			if err == nil {
+				t.Errorf("%d: Expected error, but nil", caseIndex)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, pointer) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, pointer, parsed)
+		}
+	}
+}
+
+func TestInvalidKeyTemplate(t *testing.T) {
+	for caseIndex, text := range []string{`{{`, `{{unknown .}}`} {
+		if _, err := NewTemplateKeyFormatter(text); err == nil {
+			t.Errorf("%d: Expected error, but nil", caseIndex)
+		}
+	}
+}
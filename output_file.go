@@ -0,0 +1,129 @@
+package json2csv
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OutputFile is a file which appears only when it is completely written.
+//
+// It is written to a temporary file in the same directory, which is renamed
+// to Path by Commit, or removed by Close without Commit. If Path ends with
+// ".gz", the output is compressed with gzip.
+type OutputFile struct {
+	Path  string
+	Force bool
+
+	file *os.File
+	gzip *gzip.Writer
+	w    io.Writer
+	done bool
+}
+
+// CreateOutputFile creates new OutputFile of the path.
+// It fails if the path already exists unless force.
+func CreateOutputFile(path string, force bool) (*OutputFile, error) {
+	if !force {
+		if err := checkNotExist(path); err != nil {
+			return nil, err
+		}
+	}
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp creates the file only for the owner
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	f := &OutputFile{
+		Path:  path,
+		Force: force,
+		file:  file,
+		w:     file,
+	}
+	if strings.HasSuffix(path, ".gz") {
+		f.gzip = gzip.NewWriter(file)
+		f.w = f.gzip
+	}
+	return f, nil
+}
+
+// Write writes p to the temporary file.
+func (f *OutputFile) Write(p []byte) (int, error) {
+	if f.done {
+		return 0, errors.New("Output file is already closed: " + f.Path)
+	}
+	return f.w.Write(p)
+}
+
+// Seek sets the offset of the next Write to the temporary file.
+// It fails if the output is compressed with gzip.
+func (f *OutputFile) Seek(offset int64, whence int) (int64, error) {
+	if f.done {
+		return 0, errors.New("Output file is already closed: " + f.Path)
+	}
+	if f.gzip != nil {
+		return 0, errors.New("Output file compressed with gzip is not seekable: " + f.Path)
+	}
+	return f.file.Seek(offset, whence)
+}
+
+// Commit closes the temporary file and renames it to Path.
+func (f *OutputFile) Commit() error {
+	if f.done {
+		return errors.New("Output file is already closed: " + f.Path)
+	}
+	f.done = true
+	err := f.finish()
+	if err == nil && !f.Force {
+		err = checkNotExist(f.Path)
+	}
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.Path)
+	}
+	if err != nil {
+		os.Remove(f.file.Name())
+	}
+	return err
+}
+
+// Close removes the temporary file if it is not committed.
+func (f *OutputFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.file.Close()
+	return os.Remove(f.file.Name())
+}
+
+func (f *OutputFile) finish() error {
+	var errs []error
+	if f.gzip != nil {
+		errs = append(errs, f.gzip.Close())
+	}
+	errs = append(errs, f.file.Sync(), f.file.Close())
+	return errors.Join(errs...)
+}
+
+func checkNotExist(path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("File already exists: %s", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package json2csv
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+
+	f, err := CreateOutputFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("a,b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file before Commit, but %v", err)
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a,b\n" {
+		t.Errorf("Expected %q, but %q", "a,b\n", string(b))
+	}
+
+	if _, err := CreateOutputFile(path, false); err == nil {
+		t.Errorf("Expected error for the existing file, but nil")
+	}
+
+	// Close without Commit leaves the existing file as it is.
+	f, err = CreateOutputFile(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("x\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = os.ReadFile(path)
+	if string(b) != "a,b\n" {
+		t.Errorf("Expected %q, but %q", "a,b\n", string(b))
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be removed, but %d entries", len(entries))
+	}
+}
+
+func TestOutputFileGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv.gz")
+	f, err := CreateOutputFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("a,b\n1,2\n"))
+	if _, err := f.Seek(0, io.SeekCurrent); err == nil {
+		t.Errorf("Expected error for Seek, but nil")
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a,b\n1,2\n" {
+		t.Errorf("Expected %q, but %q", "a,b\n1,2\n", string(b))
+	}
+}
+
+func TestOutputFileSeek(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	f, err := CreateOutputFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("a,b\n"))
+	if pos, err := f.Seek(0, io.SeekCurrent); err != nil || pos != 4 {
+		t.Errorf("Expected 4, but %d (%v)", pos, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("x"))
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	if string(b) != "x,b\n" {
+		t.Errorf("Expected %q, but %q", "x,b\n", string(b))
+	}
+}
@@ -0,0 +1,128 @@
+package json2csv
+
+import (
+	"sync"
+)
+
+// JSONStreamRawReader is a JSONStreamReader which can also return records
+// without decoding them, so that they are decoded in parallel.
+type JSONStreamRawReader interface {
+	JSONStreamReader
+
+	// ReadRaw returns the next record as JSON. The result must not be
+	// modified by later calls.
+	ReadRaw() []byte
+}
+
+// parallelJob is a record in the pipeline. done is closed when the worker
+// finishes it.
+type parallelJob struct {
+	raw     []byte // if isRaw
+	isRaw   bool
+	bytes   int64 // bytes read by the reader after this record
+	data    interface{}
+	rows    []KeyValue
+	records [][]string
+	err     error
+	done    chan struct{}
+}
+
+// eachParallel decodes and flattens records of the stream by the workers,
+// and also formats the rows by format if not nil. fn is called with the
+// results in the order of the stream, and it stops at the first error in
+// that order, as serial processing does. Records are not read any more
+// when ctx of p is done.
+func eachParallel(reader JSONStreamReader, path string, f *flattener, workers int, p *progress, format func(rows []KeyValue) [][]string, fn func(rows []KeyValue, records [][]string) error) error {
+	jobs := make(chan *parallelJob, workers)
+	ordered := make(chan *parallelJob, workers*4)
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// reader
+	rawReader, raw := reader.(JSONStreamRawReader)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(ordered)
+		for reader.HasNext() {
+			job := &parallelJob{done: make(chan struct{})}
+			if err := p.ctx.Err(); err != nil {
+				job.err = err
+				close(job.done)
+				select {
+				case ordered <- job:
+				case <-quit:
+				}
+				return
+			}
+			if raw {
+				job.raw, job.isRaw = rawReader.ReadRaw(), true
+			} else {
+				job.data = reader.Read()
+			}
+			job.bytes = bytesRead(reader)
+			select {
+			case ordered <- job:
+			case <-quit:
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	// workers
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.run(path, f, format)
+				close(job.done)
+			}
+		}()
+	}
+
+	for job := range ordered {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		<-job.done
+		if job.err != nil {
+			return job.err
+		}
+		p.read(job.bytes)
+		if err := fn(job.rows, job.records); err != nil {
+			return err
+		}
+		p.wrote(len(job.rows))
+	}
+	return nil
+}
+
+func (job *parallelJob) run(path string, f *flattener, format func(rows []KeyValue) [][]string) {
+	if job.isRaw {
+		if canFlattenTokens(path, f) {
+			job.rows, job.err = flattenTokens(job.raw, f)
+		} else {
+			// Decoding errors are ignored as JSONStreamReader.Read does.
+			res := make(map[string]interface{})
+			_ = decodeJSONObject(job.raw, &res)
+			job.rows, job.err = flattenRecord(res, path, f)
+		}
+		job.raw = nil
+	} else {
+		job.rows, job.err = flattenRecord(job.data, path, f)
+	}
+	if job.err == nil && format != nil {
+		job.records = format(job.rows)
+	}
+}
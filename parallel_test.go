@@ -0,0 +1,142 @@
+package json2csv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// createTestLines creates a JSON lines file of n records.
+func createTestLines(t *testing.T, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&b, `{"id": %d, "name": "user%d", "tags": ["a", "b"]}`+"\n", i, i)
+		case 1:
+			fmt.Fprintf(&b, `{"id": %d, "score": %d.5, "nested": {"x": true}}`+"\n", i, i)
+		case 2:
+			b.WriteString("\n")
+		default:
+			fmt.Fprintf(&b, `{"id": %d, "items": [{"p": 1}, {"p": 2}]}`+"\n", i)
+		}
+	}
+	filename := filepath.Join(t.TempDir(), "test.jsonl")
+	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func openTestLines(t *testing.T, filename string) JSONStreamReader {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJSONStreamLineReader(f)
+}
+
+// decodedReader hides ReadRaw of the reader.
+type decodedReader struct {
+	JSONStreamReader
+}
+
+func TestConvertStreamParallel(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	opts := &FlattenOptions{SliceLen: math.MaxInt, ArrayPolicy: JoinArray}
+	reader := openTestLines(t, filename)
+	csvHeader, err := newTestConverter(t, Options{Flatten: opts}).Header(context.Background(), reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convert := func(workers int, raw bool) string {
+		var b bytes.Buffer
+		reader := openTestLines(t, filename)
+		defer reader.Close()
+		if !raw {
+			reader = decodedReader{reader}
+		}
+		c := newTestConverter(t, Options{Flatten: opts, KeyStyle: DotBracketStyle, Workers: workers})
+		if err := c.ConvertStream(context.Background(), reader, csvHeader, &b); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	expected := convert(1, true)
+	for _, workers := range []int{2, 8} {
+		for _, raw := range []bool{true, false} {
+			if actual := convert(workers, raw); actual != expected {
+				t.Errorf("workers=%d raw=%v: Expected the same output as serial, but differs", workers, raw)
+			}
+		}
+	}
+}
+
+func TestEachRowParallel(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+	collect := func(workers int) []KeyValue {
+		reader := openTestLines(t, filename)
+		defer reader.Close()
+		rows := []KeyValue{}
+		c := newTestConverter(t, Options{Flatten: opts, Workers: workers})
+		err := c.EachRow(context.Background(), reader, func(row KeyValue) error {
+			rows = append(rows, row)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	expected := collect(1)
+	if actual := collect(4); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected the same rows as serial, but differs")
+	}
+}
+
+func TestEachRowParallelError(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+	stop := errors.New("stop")
+
+	reader := openTestLines(t, filename)
+	defer reader.Close()
+	count := 0
+	err := newTestConverter(t, Options{Flatten: opts, Workers: 4}).EachRow(context.Background(), reader, func(row KeyValue) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Expected %v, but %v", stop, err)
+	}
+	if count != 10 {
+		t.Errorf("Expected %d rows, but %d", 10, count)
+	}
+
+	// the error of the path comes in the order of the stream
+	reader = openTestLines(t, filename)
+	defer reader.Close()
+	count = 0
+	err = newTestConverter(t, Options{Path: "/id", Flatten: opts, Workers: 4}).EachRow(context.Background(), reader, func(row KeyValue) error {
+		count++
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected error, but nil")
+	}
+}
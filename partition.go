@@ -0,0 +1,200 @@
+package json2csv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Partitioner assigns rows to partitions by the value of SplitBy column,
+// and starts a new partition every MaxRows rows of the same value.
+//
+// Partition names are the values made safe for file names, followed by the
+// 1-origin sequence number if MaxRows is set, like "us", "us-2" or "3".
+// Values which are changed to be safe get a short hash of the value, like
+// "a_b@1a2b3c4d" for "a/b", so that different values have different names.
+// Null and missing values are "@null", and empty strings are "@empty".
+// Values are compared as they are written in CSV, so a number or a boolean
+// and the string of it, like 1 and "1", are the same partition.
+type Partitioner struct {
+	SplitBy string
+	MaxRows int
+
+	counts map[string]int
+}
+
+// NewPartitioner returns new Partitioner. Empty splitBy or zero maxRows
+// disables it.
+func NewPartitioner(splitBy string, maxRows int) *Partitioner {
+	return &Partitioner{
+		SplitBy: splitBy,
+		MaxRows: maxRows,
+		counts:  map[string]int{},
+	}
+}
+
+// Partition returns the partition name of the row.
+// Rows must be given in the order they are written.
+func (p *Partitioner) Partition(row KeyValue) string {
+	name := p.Value(row)
+	if p.MaxRows <= 0 {
+		return name
+	}
+
+	n := p.counts[name]/p.MaxRows + 1
+	p.counts[name]++
+	if name == "" {
+		return strconv.Itoa(n)
+	}
+	return name + "-" + strconv.Itoa(n)
+}
+
+// Value returns the partition name of the row without the sequence number.
+// It is empty if SplitBy is empty.
+func (p *Partitioner) Value(row KeyValue) string {
+	if p.SplitBy == "" {
+		return ""
+	}
+	return partitionName(row[p.SplitBy])
+}
+
+// Reset forgets the rows given so far, to partition the same rows again.
+func (p *Partitioner) Reset() {
+	p.counts = map[string]int{}
+}
+
+// partitionName returns the name of the value. Names made by it, which are
+// the hash suffix and the names of null and empty values, contain '@' which
+// is never in the safe values, so that they never collide with the values.
+func partitionName(v interface{}) string {
+	if v == nil {
+		return "@null"
+	}
+	s := toString(v)
+	if s == "" {
+		return "@empty"
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+	if name != s {
+		sum := sha256.Sum256([]byte(s))
+		name += "@" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+// PartitionWriter writes rows into partitions, each by its own CSVWriter.
+type PartitionWriter struct {
+	Partitioner *Partitioner
+
+	// Open returns the CSVWriter of the new partition and the closer of its
+	// output.
+	Open func(name string) (*CSVWriter, io.Closer, error)
+
+	// Header returns the header of the partition, where value is the name
+	// without the sequence number. It may be the union header of all
+	// partitions, but must contain all columns of the rows of the partition.
+	Header func(name, value string) CSVHeader
+
+	partitions map[string]*partitionOutput
+
+	// the last partition of each value, closed when MaxRows rolls over
+	current map[string]string
+}
+
+type partitionOutput struct {
+	writer *CSVWriter
+	closer io.Closer
+	header *Header
+}
+
+// NewPartitionWriter returns new PartitionWriter.
+func NewPartitionWriter(p *Partitioner, open func(name string) (*CSVWriter, io.Closer, error), header func(name, value string) CSVHeader) *PartitionWriter {
+	return &PartitionWriter{
+		Partitioner: p,
+		Open:        open,
+		Header:      header,
+		partitions:  map[string]*partitionOutput{},
+		current:     map[string]string{},
+	}
+}
+
+// Write writes the row to its partition.
+func (w *PartitionWriter) Write(row KeyValue) error {
+	name := w.Partitioner.Partition(row)
+	out, ok := w.partitions[name]
+	if !ok {
+		value := w.Partitioner.Value(row)
+		if prev, ok := w.current[value]; ok {
+			if err := w.closePartition(prev); err != nil {
+				return err
+			}
+		}
+		w.current[value] = name
+
+		writer, closer, err := w.Open(name)
+		if err != nil {
+			return err
+		}
+		out = &partitionOutput{writer: writer, closer: closer}
+		w.partitions[name] = out
+		if out.header, err = writer.CompileHeader(w.Header(name, value)); err != nil {
+			return err
+		}
+		if err := writer.WriteHeader(out.header); err != nil {
+			return err
+		}
+	}
+	if out.writer == nil {
+		return errors.New("Partition " + name + " is already closed")
+	}
+	return out.writer.WriteRows([]KeyValue{row}, out.header)
+}
+
+func (w *PartitionWriter) closePartition(name string) error {
+	out := w.partitions[name]
+	if out == nil || out.writer == nil {
+		return nil
+	}
+	out.writer.Flush()
+	err := errors.Join(out.writer.Error(), out.closer.Close())
+	out.writer = nil
+	return err
+}
+
+// Close flushes and closes all partitions.
+func (w *PartitionWriter) Close() error {
+	var errs []error
+	for name := range w.partitions {
+		errs = append(errs, w.closePartition(name))
+	}
+	return errors.Join(errs...)
+}
+
+// PartitionHeaders returns the header of each partition of the rows.
+// The partitioner is reset before and after.
+func PartitionHeaders(p *Partitioner, each func(fn func(row KeyValue) error) error) (map[string]CSVHeader, error) {
+	p.Reset()
+	defer p.Reset()
+	headers := map[string]CSVHeader{}
+	err := each(func(row KeyValue) error {
+		name := p.Partition(row)
+		header, ok := headers[name]
+		if !ok {
+			header = CSVHeader{}
+			headers[name] = header
+		}
+		for key := range row {
+			header[key] = ""
+		}
+		return nil
+	})
+	return headers, err
+}
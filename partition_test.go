@@ -0,0 +1,158 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func testPartitionRows() []KeyValue {
+	return []KeyValue{
+		{"/region": "us", "/id": 1, "/a": 1},
+		{"/region": "eu", "/id": 2, "/b": 2},
+		{"/region": "us", "/id": 3, "/c": 3},
+		{"/region": "us", "/id": 4},
+		{"/id": 5},
+		{"/region": "a/b c", "/id": 6},
+		{"/region": "", "/id": 7},
+	}
+}
+
+var testPartitionerCases = []struct {
+	splitBy  string
+	maxRows  int
+	expected []string
+}{
+	{"/region", 0, []string{"us", "eu", "us", "us", "@null", "a_b_c@0af99a60", "@empty"}},
+	{"/region", 2, []string{"us-1", "eu-1", "us-1", "us-2", "@null-1", "a_b_c@0af99a60-1", "@empty-1"}},
+	{"", 3, []string{"1", "1", "1", "2", "2", "2", "3"}},
+}
+
+func TestPartitioner(t *testing.T) {
+	for caseIndex, testCase := range testPartitionerCases {
+		p := NewPartitioner(testCase.splitBy, testCase.maxRows)
+		actual := []string{}
+		for _, row := range testPartitionRows() {
+			actual = append(actual, p.Partition(row))
+		}
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+var testPartitionNameCases = []struct {
+	a, b interface{}
+	same bool
+}{
+	{nil, "null", false},
+	{nil, "@null", false},
+	{"", "empty", false},
+	{"", "@empty", false},
+	{"a/b", "a_b@c14cddc0", false},
+	{json.Number("1"), "1", true},
+	{true, "true", true},
+}
+
+func TestPartitionName(t *testing.T) {
+	for caseIndex, testCase := range testPartitionNameCases {
+		a, b := partitionName(testCase.a), partitionName(testCase.b)
+		if same := a == b; same != testCase.same {
+			t.Errorf("%d: Expected %v, but %v (%q and %q)", caseIndex, testCase.same, same, a, b)
+		}
+	}
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func TestPartitionWriter(t *testing.T) {
+	rows := testPartitionRows()[:4]
+	each := func(fn func(row KeyValue) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	p := NewPartitioner("/region", 2)
+	headers, err := PartitionHeaders(p, each)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]*bytes.Buffer{}
+	open := func(name string) (*CSVWriter, io.Closer, error) {
+		b := &bytes.Buffer{}
+		outputs[name] = b
+		return NewCSVWriter(b, JSONPointerStyle, false), nopCloser{}, nil
+	}
+	header := func(name, value string) CSVHeader { return headers[name] }
+	w := NewPartitionWriter(p, open, header)
+	if err := each(w.Write); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"us-1": "/a,/c,/id,/region\n1,,1,us\n,3,3,us\n",
+		"us-2": "/id,/region\n4,us\n",
+		"eu-1": "/b,/id,/region\n2,2,eu\n",
+	}
+	actual := map[string]string{}
+	for name, b := range outputs {
+		actual[name] = b.String()
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q, but %q", expected, actual)
+	}
+}
+
+func TestPartitionWriterUniqueNames(t *testing.T) {
+	rows := []KeyValue{
+		{"/city": "東京", "/id": 1},
+		{"/city": "大阪", "/id": 2},
+		{"/city": "a/b", "/id": 3},
+		{"/city": "a_b", "/id": 4},
+		{"/city": "東京", "/id": 5},
+		{"/city": "大阪", "/id": 6},
+	}
+	outputs := map[string]*bytes.Buffer{}
+	open := func(name string) (*CSVWriter, io.Closer, error) {
+		b := &bytes.Buffer{}
+		outputs[name] = b
+		return NewCSVWriter(b, JSONPointerStyle, false), nopCloser{}, nil
+	}
+	header := func(name, value string) CSVHeader { return CSVHeader{"/city": "", "/id": ""} }
+	w := NewPartitionWriter(NewPartitioner("/city", 1), open, header)
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"__@130016b2-1":  "/city,/id\n東京,1\n",
+		"__@130016b2-2":  "/city,/id\n東京,5\n",
+		"__@6df97746-1":  "/city,/id\n大阪,2\n",
+		"__@6df97746-2":  "/city,/id\n大阪,6\n",
+		"a_b@c14cddc0-1": "/city,/id\na/b,3\n",
+		"a_b-1":          "/city,/id\na_b,4\n",
+	}
+	actual := map[string]string{}
+	for name, b := range outputs {
+		actual[name] = b.String()
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q, but %q", expected, actual)
+	}
+}
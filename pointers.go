@@ -1,8 +1,6 @@
 package json2csv
 
 import (
-	"strings"
-
 	"github.com/yukithm/json2csv/jsonpointer"
 )
 
@@ -33,18 +31,11 @@ func (pts pointers) Strings() []string {
 	return keys
 }
 
-func (pts pointers) Slashes() []string {
-	keys := make([]string, 0, pts.Len())
-	for _, p := range pts {
-		keys = append(keys, strings.Join(p.Strings(), "/"))
-	}
-	return keys
-}
-
-func (pts pointers) DotNotations(bracketIndex bool) []string {
+// Format returns keys formatted with the given KeyFormatter, such as KeyStyle.
+func (pts pointers) Format(formatter KeyFormatter) []string {
 	keys := make([]string, 0, pts.Len())
 	for _, p := range pts {
-		keys = append(keys, p.DotNotation(bracketIndex))
+		keys = append(keys, formatter.FormatKey(p))
 	}
 	return keys
 }
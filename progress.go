@@ -0,0 +1,76 @@
+package json2csv
+
+import (
+	"context"
+)
+
+// Progress is the progress of a stream conversion.
+type Progress struct {
+	// Records is the number of records read.
+	Records int64
+
+	// Bytes is the number of bytes read, if the reader is a
+	// JSONStreamByteCounter. Otherwise it is 0.
+	Bytes int64
+
+	// Rows is the number of rows written, or passed to the callback of
+	// Converter.EachRow. It is 0 while only the header or the column types
+	// are scanned.
+	Rows int64
+}
+
+// ProgressFunc is called with the progress after each record is processed.
+// It is called from the goroutine of the conversion, so it should return
+// quickly.
+type ProgressFunc func(p Progress)
+
+// JSONStreamByteCounter is a JSONStreamReader which counts bytes read.
+type JSONStreamByteCounter interface {
+	JSONStreamReader
+
+	// BytesRead returns the number of bytes of records read so far.
+	BytesRead() int64
+}
+
+// progress tracks the cancellation and the progress of a conversion.
+type progress struct {
+	Progress
+	ctx context.Context
+	fn  ProgressFunc
+}
+
+func newProgress(ctx context.Context, fn ProgressFunc) *progress {
+	return &progress{ctx: ctx, fn: fn}
+}
+
+// readRows reads the next record like readRows unless ctx is done.
+func (p *progress) readRows(reader JSONStreamReader, path string, f *flattener) ([]KeyValue, error) {
+	if err := p.ctx.Err(); err != nil {
+		return nil, err
+	}
+	rows, err := readRows(reader, path, f)
+	p.read(bytesRead(reader))
+	return rows, err
+}
+
+// read counts a record, where bytes is the total bytes read.
+func (p *progress) read(bytes int64) {
+	p.Records++
+	p.Bytes = bytes
+}
+
+// wrote counts rows written, and reports the progress.
+func (p *progress) wrote(rows int) {
+	p.Rows += int64(rows)
+	if p.fn != nil {
+		p.fn(p.Progress)
+	}
+}
+
+// bytesRead returns the bytes read by the reader, or 0 if not counted.
+func bytesRead(reader JSONStreamReader) int64 {
+	if counter, ok := reader.(JSONStreamByteCounter); ok {
+		return counter.BytesRead()
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package json2csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+
+	var last Progress
+	reader := openTestLines(t, filename)
+	c := newTestConverter(t, Options{Flatten: opts, Progress: func(p Progress) { last = p }})
+	csvHeader, err := c.Header(context.Background(), reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Progress{Records: 1000, Bytes: info.Size()}); last != expected {
+		t.Errorf("Expected %+v, but %+v", expected, last)
+	}
+
+	for _, workers := range []int{1, 4} {
+		var last Progress
+		count := 0
+		reader := openTestLines(t, filename)
+		c := newTestConverter(t, Options{Flatten: opts, Workers: workers, Progress: func(p Progress) {
+			if p.Records != last.Records+1 || p.Bytes < last.Bytes || p.Rows < last.Rows {
+				t.Errorf("workers=%d: Expected progress after %+v, but %+v", workers, last, p)
+			}
+			last = p
+			count++
+		}})
+		err := c.ConvertStream(context.Background(), reader, csvHeader, &bytes.Buffer{})
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every 4th line is empty
+		if expected := (Progress{Records: 1000, Bytes: info.Size(), Rows: 750}); last != expected {
+			t.Errorf("workers=%d: Expected %+v, but %+v", workers, expected, last)
+		}
+		if count != 1000 {
+			t.Errorf("workers=%d: Expected %d calls, but %d", workers, 1000, count)
+		}
+	}
+}
+
+func TestProgressZip(t *testing.T) {
+	zipReader, err := zip.OpenReader(createTestZip(t, testStreamJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewJSONStreamZipReader(zipReader).(*JSONStreamZipReader)
+	defer reader.Close()
+	total := reader.BytesTotal()
+	if total <= 0 {
+		t.Fatalf("Expected positive total, but %d", total)
+	}
+	var last Progress
+	c := newTestConverter(t, Options{Progress: func(p Progress) { last = p }})
+	if _, err := c.Header(context.Background(), reader); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Progress{Records: int64(len(testStreamJSON)), Bytes: total}); last != expected {
+		t.Errorf("Expected %+v, but %+v", expected, last)
+	}
+}
+
+func TestConvertStreamCancel(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+	reader := openTestLines(t, filename)
+	csvHeader, err := newTestConverter(t, Options{Flatten: opts}).Header(context.Background(), reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var b bytes.Buffer
+		var last Progress
+		reader := openTestLines(t, filename)
+		c := newTestConverter(t, Options{Flatten: opts, Workers: workers, Progress: func(p Progress) {
+			last = p
+			if p.Records == 10 {
+				cancel()
+			}
+		}})
+		err := c.ConvertStream(ctx, reader, csvHeader, &b)
+		reader.Close()
+		cancel()
+		if err != context.Canceled {
+			t.Errorf("workers=%d: Expected %v, but %v", workers, context.Canceled, err)
+		}
+		if last.Records != 10 {
+			t.Errorf("workers=%d: Expected %d records, but %d", workers, 10, last.Records)
+		}
+		// the header and the rows written before the cancellation
+		if lines := strings.Count(b.String(), "\n"); lines != int(last.Rows)+1 {
+			t.Errorf("workers=%d: Expected %d lines, but %d", workers, last.Rows+1, lines)
+		}
+	}
+}
+
+func TestEachRowCancel(t *testing.T) {
+	filename := createTestLines(t, 1000)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader := openTestLines(t, filename)
+		count := 0
+		c := newTestConverter(t, Options{Flatten: opts, Workers: workers})
+		err := c.EachRow(ctx, reader, func(row KeyValue) error {
+			count++
+			if count == 5 {
+				cancel()
+			}
+			return nil
+		})
+		reader.Close()
+		cancel()
+		if err != context.Canceled {
+			t.Errorf("workers=%d: Expected %v, but %v", workers, context.Canceled, err)
+		}
+		if count != 5 {
+			t.Errorf("workers=%d: Expected %d rows, but %d", workers, 5, count)
+		}
+	}
+
+	// a canceled context stops before reading
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader := openTestLines(t, filename)
+	defer reader.Close()
+	if _, err := newTestConverter(t, Options{Flatten: opts}).Header(ctx, reader); err != context.Canceled {
+		t.Errorf("Expected %v, but %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,136 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Renamer renames header keys to display names.
+//
+// Each rule maps a JSON Pointer to a name. "*" in the pointer matches any
+// token, and "{n}" in the name is replaced with the token matched by the n-th
+// "*" (1-origin). For example, "/items/*/sku" to "item_{1}_sku" renames
+// "/items/0/sku" to "item_0_sku".
+type Renamer struct {
+	rules []renameRule
+}
+
+type renameRule struct {
+	pattern jsonpointer.JSONPointer
+	name    string
+}
+
+var renamePlaceholder = regexp.MustCompile(`\{(\d+)\}`)
+
+// NewRenamer returns new Renamer with the given pointer to name mapping.
+// Specific pointers take precedence over pointers with wildcards.
+func NewRenamer(mapping map[string]string) (*Renamer, error) {
+	r := &Renamer{}
+	for pointer, name := range mapping {
+		pattern, err := jsonpointer.New(pointer)
+		if err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, renameRule{pattern, name})
+	}
+	sort.Slice(r.rules, func(i, j int) bool {
+		return lessPattern(r.rules[i].pattern, r.rules[j].pattern)
+	})
+	return r, nil
+}
+
+// ParseRenamer reads the mapping and returns new Renamer.
+//
+// The mapping is a JSON object, or lines of "POINTER = NAME".
+// Empty lines and lines starting with "#" are ignored.
+func ParseRenamer(r io.Reader) (*Renamer, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := map[string]string{}
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		if err := json.Unmarshal(content, &mapping); err != nil {
+			return nil, err
+		}
+		return NewRenamer(mapping)
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid rename rule at line %d: %q", n, line)
+		}
+		mapping[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return NewRenamer(mapping)
+}
+
+// Rename returns the display name of the pointer.
+// It returns false if no rules match.
+func (r *Renamer) Rename(pointer jsonpointer.JSONPointer) (string, bool) {
+	for _, rule := range r.rules {
+		if !matchPointer(rule.pattern, pointer) {
+			continue
+		}
+		captures := []string{}
+		for i, token := range rule.pattern {
+			if token == "*" {
+				captures = append(captures, string(pointer[i]))
+			}
+		}
+		return renamePlaceholder.ReplaceAllStringFunc(rule.name, func(m string) string {
+			n, _ := strconv.Atoi(m[1 : len(m)-1])
+			if n < 1 || n > len(captures) {
+				return m
+			}
+			return captures[n-1]
+		}), true
+	}
+	return "", false
+}
+
+// formatHeader formats keys with the formatter, and renames them if renamer
+// is not nil. It is an error if a renamed key has the same name as another.
+func formatHeader(pts pointers, formatter KeyFormatter, renamer *Renamer) ([]string, error) {
+	header := pts.Format(formatter)
+	if renamer == nil {
+		return header, nil
+	}
+	renamed := make([]bool, len(pts))
+	for i, p := range pts {
+		if name, ok := renamer.Rename(p); ok {
+			header[i] = name
+			renamed[i] = true
+		}
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		j, ok := index[name]
+		if ok && (renamed[i] || renamed[j]) {
+			return nil, fmt.Errorf("Both %s and %s are named %q", pts[j], pts[i], name)
+		}
+		if !ok {
+			index[name] = i
+		}
+	}
+	return header, nil
+}
@@ -0,0 +1,141 @@
+package json2csv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+var testRenameMapping = map[string]string{
+	"/customer/billing_address/postal_code": "Postal Code",
+	"/items/*/sku":                          "item_{1}_sku",
+	"/items/0/sku":                          "First SKU",
+	"/*/*/name":                             "{1} {2} name",
+}
+
+var testRenameCases = []struct {
+	pointer  string
+	expected string
+	ok       bool
+}{
+	{"/customer/billing_address/postal_code", "Postal Code", true},
+	{"/items/0/sku", "First SKU", true},
+	{"/items/1/sku", "item_1_sku", true},
+	{"/items/1/name", "items 1 name", true},
+	{"/items/1", "", false},
+	{"/customer/name", "", false},
+}
+
+func mustPointer(t *testing.T, s string) jsonpointer.JSONPointer {
+	t.Helper()
+	p, err := jsonpointer.New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRename(t *testing.T) {
+	r, err := NewRenamer(testRenameMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for caseIndex, testCase := range testRenameCases {
+		actual, ok := r.Rename(mustPointer(t, testCase.pointer))
+		if ok != testCase.ok || actual != testCase.expected {
+			t.Errorf("%d: Expected %q(%v), but %q(%v)", caseIndex, testCase.expected, testCase.ok, actual, ok)
+		}
+	}
+}
+
+var testParseRenamerCases = []struct {
+	input     string
+	expected  string
+	expectErr bool
+}{
+	{"# comment\n\n/items/*/sku = item_{1}_sku\n", "item_2_sku", false},
+	{`{"/items/*/sku": "SKU {1}"}`, "SKU 2", false},
+	{"/items/*/sku item_{1}_sku\n", "", true},
+	{"items/*/sku = sku\n", "", true},
+}
+
+func TestParseRenamer(t *testing.T) {
+	for caseIndex, testCase := range testParseRenamerCases {
+		r, err := ParseRenamer(strings.NewReader(testCase.input))
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("%d: Expected error, but nil", caseIndex)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		actual, _ := r.Rename(mustPointer(t, "/items/2/sku"))
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestCSVWriterRenamer(t *testing.T) {
+	r, err := NewRenamer(testRenameMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []KeyValue{
+		{"/items/0/sku": "A", "/items/1/sku": "B", "/id": 1},
+	}
+	expected := map[KeyStyle]string{
+		JSONPointerStyle: "/id,First SKU,item_1_sku\n1,A,B\n",
+		SlashStyle:       "id,First SKU,item_1_sku\n1,A,B\n",
+		DotNotationStyle: "id,First SKU,item_1_sku\n1,A,B\n",
+		DotBracketStyle:  "id,First SKU,item_1_sku\n1,A,B\n",
+	}
+	for style, want := range expected {
+		b := &bytes.Buffer{}
+		w := NewCSVWriter(b, style, false)
+		w.Renamer = r
+		if err := w.WriteCSV(results); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: Expected %q, but %q", style, want, got)
+		}
+	}
+}
+
+var testRenameCollisionCases = []struct {
+	mapping map[string]string
+	row     KeyValue
+	ok      bool
+}{
+	{map[string]string{"/a": "x", "/b": "x"}, KeyValue{"/a": 1, "/b": 2}, false},
+	{map[string]string{"/*/id": "id"}, KeyValue{"/a/id": 1, "/b/id": 2}, false},
+	{map[string]string{"/a": "b"}, KeyValue{"/a": 1, "/b": 2}, false},
+	{map[string]string{"/a": "x", "/b": "x"}, KeyValue{"/a": 1, "/c": 2}, true},
+	{map[string]string{"/a": "a"}, KeyValue{"/a": 1, "/b": 2}, true},
+}
+
+func TestRenameCollision(t *testing.T) {
+	for caseIndex, testCase := range testRenameCollisionCases {
+		r, err := NewRenamer(testCase.mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewCSVWriter(&bytes.Buffer{}, SlashStyle, false)
+		w.Renamer = r
+		_, err = w.CompileHeader(CSVHeader(testCase.row))
+		if ok := err == nil; ok != testCase.ok {
+			t.Errorf("%d: Expected %v, but %v (%v)", caseIndex, testCase.ok, ok, err)
+		}
+		err = w.WriteCSV([]KeyValue{testCase.row})
+		if ok := err == nil; ok != testCase.ok {
+			t.Errorf("%d: Expected %v, but %v (%v)", caseIndex, testCase.ok, ok, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package json2csv
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Default columns of keys and values of Melt and Pivot.
+const (
+	DefaultKeyColumn   = "/key"
+	DefaultValueColumn = "/value"
+)
+
+// Melt converts the columns under Pointer into rows (wide to long).
+//
+// Each row is repeated for every column under Pointer, with KeyColumn set to
+// the pointer of the column relative to Pointer (without the leading "/")
+// and ValueColumn set to its value. For example, melting "/metrics" of
+// {"/id": 1, "/metrics/cpu": 1, "/metrics/mem": 2} gives
+// {"/id": 1, "/key": "cpu", "/value": 1} and {"/id": 1, "/key": "mem", "/value": 2}.
+// Rows that have no columns under Pointer are kept as they are.
+type Melt struct {
+	Pointer     jsonpointer.JSONPointer
+	KeyColumn   string
+	ValueColumn string
+}
+
+// NewMelt returns new Melt of the pointer with the default columns.
+func NewMelt(pointer string) (*Melt, error) {
+	p, err := jsonpointer.New(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return &Melt{
+		Pointer:     p,
+		KeyColumn:   DefaultKeyColumn,
+		ValueColumn: DefaultValueColumn,
+	}, nil
+}
+
+// Apply melts the rows.
+func (m *Melt) Apply(rows []KeyValue) ([]KeyValue, error) {
+	results := make([]KeyValue, 0, len(rows))
+	for _, row := range rows {
+		base := KeyValue{}
+		var melted pointers
+		for key, value := range row {
+			pointer, err := jsonpointer.New(key)
+			if err != nil {
+				return nil, err
+			}
+			if hasPrefixPointer(pointer, m.Pointer) {
+				melted = append(melted, pointer)
+			} else {
+				base[key] = value
+			}
+		}
+		if len(melted) == 0 {
+			results = append(results, row)
+			continue
+		}
+
+		sort.Sort(melted)
+		for _, pointer := range melted {
+			result := make(KeyValue, len(base)+2)
+			for k, v := range base {
+				result[k] = v
+			}
+			result[m.KeyColumn] = strings.TrimPrefix(pointer[len(m.Pointer):].String(), "/")
+			result[m.ValueColumn] = row[pointer.String()]
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Pivot converts rows of keys and values into columns under Pointer
+// (long to wide), which is the reverse of Melt of columns directly under
+// Pointer.
+//
+// Rows are grouped by the columns other than KeyColumn and ValueColumn, in
+// the order of their first appearance. Each row of a group adds the column
+// of the key as a token under Pointer, so "a/b" becomes Pointer + "/a~1b".
+// Rows without ValueColumn add no columns. If a key appears more than once in
+// a group, the last value is used.
+type Pivot struct {
+	Pointer     jsonpointer.JSONPointer
+	KeyColumn   string
+	ValueColumn string
+}
+
+// NewPivot returns new Pivot of the pointer with the default columns.
+func NewPivot(pointer string) (*Pivot, error) {
+	p, err := jsonpointer.New(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return &Pivot{
+		Pointer:     p,
+		KeyColumn:   DefaultKeyColumn,
+		ValueColumn: DefaultValueColumn,
+	}, nil
+}
+
+// Apply pivots the rows.
+func (p *Pivot) Apply(rows []KeyValue) ([]KeyValue, error) {
+	groups := map[string]KeyValue{}
+	results := []KeyValue{}
+	for _, row := range rows {
+		keys := make([]string, 0, len(row))
+		for key := range row {
+			if key != p.KeyColumn && key != p.ValueColumn {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var id strings.Builder
+		for _, key := range keys {
+			id.WriteString(key)
+			id.WriteString("\x00")
+			writeValueKey(&id, row[key])
+		}
+		result, ok := groups[id.String()]
+		if !ok {
+			result = make(KeyValue, len(keys))
+			for _, key := range keys {
+				result[key] = row[key]
+			}
+			groups[id.String()] = result
+			results = append(results, result)
+		}
+
+		key, ok := row[p.KeyColumn]
+		if !ok || key == nil {
+			continue
+		}
+		value, ok := row[p.ValueColumn]
+		if !ok {
+			continue
+		}
+		pointer := append(p.Pointer.Clone(), jsonpointer.Token(toString(key)))
+		result[pointer.String()] = value
+	}
+	return results, nil
+}
+
+// hasPrefixPointer reports whether pointer is under prefix.
+func hasPrefixPointer(pointer, prefix jsonpointer.JSONPointer) bool {
+	if len(pointer) <= len(prefix) {
+		return false
+	}
+	for i, token := range prefix {
+		if pointer[i] != token {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testWideRows() []KeyValue {
+	return []KeyValue{
+		{"/id": json.Number("1"), "/metrics/cpu": json.Number("1"), "/metrics/mem": json.Number("2")},
+		{"/id": json.Number("2"), "/metrics/disk/read": json.Number("3"), "/metrics/a~1b": json.Number("4")},
+		{"/id": json.Number("3")},
+	}
+}
+
+func testLongRows() []KeyValue {
+	return []KeyValue{
+		{"/id": json.Number("1"), "/key": "cpu", "/value": json.Number("1")},
+		{"/id": json.Number("1"), "/key": "mem", "/value": json.Number("2")},
+		{"/id": json.Number("2"), "/key": "a~1b", "/value": json.Number("4")},
+		{"/id": json.Number("2"), "/key": "disk/read", "/value": json.Number("3")},
+		{"/id": json.Number("3")},
+	}
+}
+
+func TestMelt(t *testing.T) {
+	m, err := NewMelt("/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := m.Apply(testWideRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := testLongRows(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	p, err := NewPivot("/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := p.Apply(testLongRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys are tokens, not pointers
+	expected := []KeyValue{
+		{"/id": json.Number("1"), "/metrics/cpu": json.Number("1"), "/metrics/mem": json.Number("2")},
+		{"/id": json.Number("2"), "/metrics/disk~1read": json.Number("3"), "/metrics/a~01b": json.Number("4")},
+		{"/id": json.Number("3")},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestPivotColumns(t *testing.T) {
+	p, _ := NewPivot("")
+	p.KeyColumn = "/name"
+	p.ValueColumn = "/v"
+	rows := []KeyValue{
+		{"/host": "a", "/name": "cpu", "/v": 1},
+		{"/host": "b", "/name": "cpu", "/v": 2},
+		{"/host": "a", "/name": "cpu", "/v": 3},
+		{"/host": "a", "/name": "mem", "/v": 4},
+	}
+	actual, err := p.Apply(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeyValue{
+		{"/host": "a", "/cpu": 3, "/mem": 4},
+		{"/host": "b", "/cpu": 2},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestPivotMissingValue(t *testing.T) {
+	p, _ := NewPivot("/m")
+	rows := []KeyValue{
+		{"/id": 1, "/key": "a", "/value": 1},
+		{"/id": 1, "/key": "b"},
+		{"/id": 2, "/key": "b"},
+	}
+	actual, err := p.Apply(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeyValue{{"/id": 1, "/m/a": 1}, {"/id": 2}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+
+	var b bytes.Buffer
+	if err := NewCSVWriter(&b, JSONPointerStyle, false).WriteCSV(actual); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/id,/m/a\n1,1\n2,\n"; b.String() != want {
+		t.Errorf("Expected %q, but %q", want, b.String())
+	}
+}
+
+func TestJSON2CSVMelt(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"id": 1, "metrics": {"cpu": 1, "mem": 2}}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	opts := NewFlattenOptions()
+	opts.Melt, _ = NewMelt("/metrics")
+	header := CSVHeader{}
+	results, err := JSON2CSVWithOptions(data, header, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 rows, but %d", len(results))
+	}
+	if expected := (CSVHeader{"/id": "", "/key": "", "/value": ""}); !reflect.DeepEqual(header, expected) {
+		t.Errorf("Expected %v, but %v", expected, header)
+	}
+}
@@ -0,0 +1,199 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// DefaultMaxSamples is the default number of sample values of each column.
+const DefaultMaxSamples = 3
+
+// ColumnSchema represents the inferred schema of a column.
+type ColumnSchema struct {
+	Key       string
+	Type      ColumnType
+	Nullable  bool
+	MinLength int
+	MaxLength int
+	Samples   []string
+
+	count int
+}
+
+// Schema represents the inferred schema of the CSV.
+type Schema struct {
+	Columns []*ColumnSchema
+	Rows    int
+}
+
+// SchemaBuilder infers the schema from rows.
+type SchemaBuilder struct {
+	MaxSamples int
+
+	rows    int
+	columns map[string]*ColumnSchema
+}
+
+// NewSchemaBuilder returns new SchemaBuilder.
+func NewSchemaBuilder() *SchemaBuilder {
+	return &SchemaBuilder{
+		MaxSamples: DefaultMaxSamples,
+		columns:    map[string]*ColumnSchema{},
+	}
+}
+
+// Update updates the schema with the given rows.
+func (b *SchemaBuilder) Update(results []KeyValue) {
+	for _, result := range results {
+		b.rows++
+		for key, value := range result {
+			col, ok := b.columns[key]
+			if !ok {
+				col = &ColumnSchema{Key: key, Type: TypeOfValue(value), MinLength: -1}
+				b.columns[key] = col
+			}
+			col.Type = col.Type.Merge(TypeOfValue(value))
+			if value == nil {
+				col.Nullable = true
+				continue
+			}
+			col.count++
+
+			s := toString(value)
+			length := utf8.RuneCountInString(s)
+			if col.MinLength < 0 || length < col.MinLength {
+				col.MinLength = length
+			}
+			if length > col.MaxLength {
+				col.MaxLength = length
+			}
+			if len(col.Samples) < b.MaxSamples && !containsString(col.Samples, s) {
+				col.Samples = append(col.Samples, s)
+			}
+		}
+	}
+}
+
+// Schema returns the inferred schema. Columns are ordered as the CSV header.
+func (b *SchemaBuilder) Schema() (*Schema, error) {
+	pts := make(pointers, 0, len(b.columns))
+	for key := range b.columns {
+		pointer, err := jsonpointer.New(key)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, pointer)
+	}
+	sort.Sort(pts)
+
+	schema := &Schema{Rows: b.rows}
+	for _, key := range pts.Strings() {
+		col := *b.columns[key]
+		if col.count < b.rows {
+			col.Nullable = true
+		}
+		if col.MinLength < 0 {
+			col.MinLength = 0
+		}
+		schema.Columns = append(schema.Columns, &col)
+	}
+	return schema, nil
+}
+
+// UpdateStream updates the schema with all rows in the stream.
+func (b *SchemaBuilder) UpdateStream(reader JSONStreamReader, path string, opts *FlattenOptions) error {
+	f, err := newFlattener(opts)
+	if err != nil {
+		return err
+	}
+	for reader.HasNext() {
+		results, err := readRows(reader, path, f)
+		if err != nil {
+			return err
+		}
+		b.Update(results)
+	}
+	return nil
+}
+
+// InferSchema scans the stream and infers the schema of each column.
+func InferSchema(reader JSONStreamReader, path string, sliceLen int) (*Schema, error) {
+	builder := NewSchemaBuilder()
+	if err := builder.UpdateStream(reader, path, &FlattenOptions{SliceLen: sliceLen}); err != nil {
+		return nil, err
+	}
+	return builder.Schema()
+}
+
+var jsonSchemaTypes = map[ColumnType]string{
+	NullColumn:    "null",
+	IntegerColumn: "integer",
+	FloatColumn:   "number",
+	BoolColumn:    "boolean",
+	StringColumn:  "string",
+}
+
+// JSONSchema returns a JSON Schema which describes each row of the CSV.
+// Property names are formatted with the given KeyFormatter, such as KeyStyle.
+func (s *Schema) JSONSchema(style KeyFormatter) ([]byte, error) {
+	names, err := s.Names(style)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]interface{}, len(s.Columns))
+	required := []string{}
+	for i, col := range s.Columns {
+		prop := map[string]interface{}{}
+		if t, ok := jsonSchemaTypes[col.Type]; ok {
+			if col.Nullable && col.Type != NullColumn {
+				prop["type"] = []string{t, "null"}
+			} else {
+				prop["type"] = t
+			}
+		}
+		if col.Type == StringColumn {
+			prop["minLength"] = col.MinLength
+			prop["maxLength"] = col.MaxLength
+		}
+		if len(col.Samples) > 0 {
+			prop["examples"] = col.Samples
+		}
+		properties[names[i]] = prop
+		if !col.Nullable {
+			required = append(required, names[i])
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, "", "  ")
+}
+
+// Names returns column names formatted with the given KeyFormatter, such as KeyStyle.
+func (s *Schema) Names(style KeyFormatter) ([]string, error) {
+	pts := make(pointers, 0, len(s.Columns))
+	for _, col := range s.Columns {
+		pointer, err := jsonpointer.New(col.Key)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, pointer)
+	}
+	return pts.Format(style), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package json2csv
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	zipReader, err := zip.OpenReader(createTestZip(t, []string{
+		`{"id": 1, "name": "foo", "score": 1, "mixed": 1}`,
+		`{"id": 2, "name": "barbaz", "score": 1.5, "mixed": "x"}`,
+		`{"id": 3, "name": "foo", "flag": true}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zipReader.Close()
+
+	schema, err := InferSchema(NewJSONStreamZipReader(zipReader), "", math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*ColumnSchema{
+		{Key: "/flag", Type: BoolColumn, Nullable: true, MinLength: 4, MaxLength: 4, Samples: []string{"true"}, count: 1},
+		{Key: "/id", Type: IntegerColumn, MinLength: 1, MaxLength: 1, Samples: []string{"1", "2", "3"}, count: 3},
+		{Key: "/mixed", Type: MixedColumn, Nullable: true, MinLength: 1, MaxLength: 1, Samples: []string{"1", "x"}, count: 2},
+		{Key: "/name", Type: StringColumn, MinLength: 3, MaxLength: 6, Samples: []string{"foo", "barbaz"}, count: 3},
+		{Key: "/score", Type: FloatColumn, Nullable: true, MinLength: 1, MaxLength: 3, Samples: []string{"1", "1.5"}, count: 2},
+	}
+	if schema.Rows != 3 {
+		t.Errorf("Expected 3 rows, but %d", schema.Rows)
+	}
+	if !reflect.DeepEqual(expected, schema.Columns) {
+		for i, col := range schema.Columns {
+			t.Errorf("%d: %+v", i, col)
+		}
+	}
+
+	b, err := schema.JSONSchema(DotNotationStyle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatal(err)
+	}
+	properties := actual["properties"].(map[string]interface{})
+	expectedTypes := map[string]interface{}{
+		"flag":  []interface{}{"boolean", "null"},
+		"id":    "integer",
+		"mixed": nil,
+		"name":  "string",
+		"score": []interface{}{"number", "null"},
+	}
+	for name, expectedType := range expectedTypes {
+		prop := properties[name].(map[string]interface{})
+		if !reflect.DeepEqual(expectedType, prop["type"]) {
+			t.Errorf("%s: Expected %v, but %v", name, expectedType, prop["type"])
+		}
+	}
+	if !reflect.DeepEqual([]interface{}{"id", "name"}, actual["required"]) {
+		t.Errorf("Unexpected required: %v", actual["required"])
+	}
+}
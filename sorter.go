@@ -0,0 +1,310 @@
+package json2csv
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// DefaultSortChunkSize is the default number of rows that ExternalSorter
+// sorts in memory.
+const DefaultSortChunkSize = 100000
+
+// SortKey is a column to sort rows by.
+type SortKey struct {
+	Key        string
+	Descending bool
+}
+
+// RowSorter sorts rows by one or more columns.
+//
+// Numbers are compared numerically, strings lexicographically and false is
+// less than true. Values of different types are ordered as booleans, numbers
+// and strings. Missing values are null, which come first unless NullsLast.
+type RowSorter struct {
+	Keys      []SortKey
+	NullsLast bool
+}
+
+// NewRowSorter returns new RowSorter with comma separated keys like
+// "/created_at,-/id". A key prefixed with "-" is sorted in descending order.
+func NewRowSorter(spec string) (*RowSorter, error) {
+	s := &RowSorter{}
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		descending := strings.HasPrefix(key, "-")
+		key = strings.TrimPrefix(key, "-")
+		if _, err := jsonpointer.New(key); err != nil || key == "" {
+			return nil, fmt.Errorf("Invalid sort key %q", key)
+		}
+		s.Keys = append(s.Keys, SortKey{Key: key, Descending: descending})
+	}
+	return s, nil
+}
+
+// Sort sorts rows. The order of equal rows is preserved.
+func (s *RowSorter) Sort(rows []KeyValue) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return s.Less(rows[i], rows[j])
+	})
+}
+
+// Less reports whether row a should sort before row b.
+func (s *RowSorter) Less(a, b KeyValue) bool {
+	for _, key := range s.Keys {
+		x, y := a[key.Key], b[key.Key]
+		if x == nil || y == nil {
+			if (x == nil) == (y == nil) {
+				continue
+			}
+			// nulls don't depend on the direction
+			return (x == nil) != s.NullsLast
+		}
+		c := compareValues(x, y)
+		if c == 0 {
+			continue
+		}
+		if key.Descending {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// compareValues compares non-null values, and returns -1, 0 or +1.
+func compareValues(x, y interface{}) int {
+	rx, ry := sortRank(x), sortRank(y)
+	if rx != ry {
+		return compareInts(int64(rx), int64(ry))
+	}
+
+	switch rx {
+	case 1:
+		return compareInts(boolInt(x.(bool)), boolInt(y.(bool)))
+	case 2:
+		return compareNumbers(x, y)
+	default:
+		return strings.Compare(toString(x), toString(y))
+	}
+}
+
+// sortRank returns the order of the type: booleans, numbers and others.
+func sortRank(v interface{}) int {
+	switch v.(type) {
+	case bool:
+		return 1
+	case json.Number, int, int64, uint64, float64:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func compareNumbers(x, y interface{}) int {
+	if ix, err := strconv.ParseInt(toString(x), 10, 64); err == nil {
+		if iy, err := strconv.ParseInt(toString(y), 10, 64); err == nil {
+			return compareInts(ix, iy)
+		}
+	}
+	fx, _ := toFloat64(x)
+	fy, _ := toFloat64(y)
+	switch {
+	case fx < fy:
+		return -1
+	case fx > fy:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInts(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	gob.Register(json.Number(""))
+}
+
+// ExternalSorter sorts rows which don't fit in memory.
+//
+// Rows are sorted in chunks of ChunkSize rows, and each chunk is written to
+// a temporary file. Each merges the chunks. Call Close to remove the files.
+type ExternalSorter struct {
+	Sorter    *RowSorter
+	ChunkSize int
+
+	// TempDir is the directory of the temporary files.
+	// If empty, the default directory for temporary files is used.
+	TempDir string
+
+	rows   []KeyValue
+	chunks []*os.File
+}
+
+// NewExternalSorter returns new ExternalSorter.
+func NewExternalSorter(sorter *RowSorter, chunkSize int) *ExternalSorter {
+	return &ExternalSorter{
+		Sorter:    sorter,
+		ChunkSize: chunkSize,
+	}
+}
+
+// Add adds the row.
+func (s *ExternalSorter) Add(row KeyValue) error {
+	s.rows = append(s.rows, row)
+	if len(s.rows) >= s.ChunkSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush writes the sorted rows in memory to a temporary file.
+func (s *ExternalSorter) flush() error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	s.Sorter.Sort(s.rows)
+
+	f, err := os.CreateTemp(s.TempDir, "json2csv-sort-*")
+	if err != nil {
+		return err
+	}
+	s.chunks = append(s.chunks, f)
+	w := bufio.NewWriter(f)
+	e := gob.NewEncoder(w)
+	for _, row := range s.rows {
+		if err := e.Encode(row); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	s.rows = s.rows[:0]
+	return nil
+}
+
+// Each calls fn for each row in sorted order.
+func (s *ExternalSorter) Each(fn func(row KeyValue) error) error {
+	if len(s.chunks) == 0 {
+		// everything is in memory
+		s.Sorter.Sort(s.rows)
+		for _, row := range s.rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	h := &chunkHeap{sorter: s.Sorter}
+	for i, f := range s.chunks {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		c := &chunkReader{index: i, decoder: gob.NewDecoder(bufio.NewReader(f))}
+		if ok, err := c.next(); err != nil {
+			return err
+		} else if ok {
+			h.chunks = append(h.chunks, c)
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		c := h.chunks[0]
+		if err := fn(c.row); err != nil {
+			return err
+		}
+		if ok, err := c.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// Close removes the temporary files.
+func (s *ExternalSorter) Close() error {
+	var errs []error
+	for _, f := range s.chunks {
+		errs = append(errs, f.Close(), os.Remove(f.Name()))
+	}
+	s.chunks = nil
+	s.rows = nil
+	return errors.Join(errs...)
+}
+
+type chunkReader struct {
+	index   int
+	decoder *gob.Decoder
+	row     KeyValue
+}
+
+func (c *chunkReader) next() (bool, error) {
+	c.row = nil
+	if err := c.decoder.Decode(&c.row); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// chunkHeap merges chunks. Rows of earlier chunks come first if they are
+// equal, so the sort is stable.
+type chunkHeap struct {
+	sorter *RowSorter
+	chunks []*chunkReader
+}
+
+func (h *chunkHeap) Len() int      { return len(h.chunks) }
+func (h *chunkHeap) Swap(i, j int) { h.chunks[i], h.chunks[j] = h.chunks[j], h.chunks[i] }
+func (h *chunkHeap) Less(i, j int) bool {
+	a, b := h.chunks[i], h.chunks[j]
+	if h.sorter.Less(a.row, b.row) {
+		return true
+	}
+	if h.sorter.Less(b.row, a.row) {
+		return false
+	}
+	return a.index < b.index
+}
+func (h *chunkHeap) Push(x interface{}) { h.chunks = append(h.chunks, x.(*chunkReader)) }
+func (h *chunkHeap) Pop() interface{} {
+	c := h.chunks[len(h.chunks)-1]
+	h.chunks = h.chunks[:len(h.chunks)-1]
+	return c
+}
@@ -0,0 +1,117 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testSortRows() []KeyValue {
+	return []KeyValue{
+		{"/id": json.Number("1"), "/name": "b", "/score": json.Number("10")},
+		{"/id": json.Number("2"), "/name": "a", "/score": json.Number("9")},
+		{"/id": json.Number("3"), "/name": "a"},
+		{"/id": json.Number("4"), "/name": "c", "/score": json.Number("1e2")},
+		{"/id": json.Number("5"), "/name": "a", "/score": json.Number("9.5")},
+		{"/id": json.Number("6"), "/name": nil, "/score": json.Number("9")},
+	}
+}
+
+var testRowSorterCases = []struct {
+	spec      string
+	nullsLast bool
+	expected  []string
+}{
+	{"/score", false, []string{"3", "2", "6", "5", "1", "4"}},
+	{"-/score", false, []string{"3", "4", "1", "5", "2", "6"}},
+	{"-/score", true, []string{"4", "1", "5", "2", "6", "3"}},
+	{"/name,-/id", false, []string{"6", "5", "3", "2", "1", "4"}},
+	{"/name, /score", true, []string{"2", "5", "3", "1", "4", "6"}},
+}
+
+func sortedIDs(rows []KeyValue) []string {
+	ids := []string{}
+	for _, row := range rows {
+		ids = append(ids, toString(row["/id"]))
+	}
+	return ids
+}
+
+func TestRowSorter(t *testing.T) {
+	for caseIndex, testCase := range testRowSorterCases {
+		sorter, err := NewRowSorter(testCase.spec)
+		if err != nil {
+			t.Errorf("%d: Unexpected error: %v", caseIndex, err)
+			continue
+		}
+		sorter.NullsLast = testCase.nullsLast
+		rows := testSortRows()
+		sorter.Sort(rows)
+		if actual := sortedIDs(rows); !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestInvalidSortKey(t *testing.T) {
+	for caseIndex, spec := range []string{"", "id", "/id,", "-"} {
+		if _, err := NewRowSorter(spec); err == nil {
+			t.Errorf("%d: Expected error, but nil", caseIndex)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	cases := []struct {
+		x, y     interface{}
+		expected int
+	}{
+		{json.Number("10"), json.Number("9"), 1},
+		{json.Number("9007199254740993"), json.Number("9007199254740992"), 1},
+		{json.Number("1.5"), int64(2), -1},
+		{"10", "9", -1},
+		{false, true, -1},
+		{true, json.Number("0"), -1},
+		{json.Number("1"), "1", -1},
+	}
+	for caseIndex, testCase := range cases {
+		if actual := compareValues(testCase.x, testCase.y); actual != testCase.expected {
+			t.Errorf("%d: Expected %d, but %d", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestExternalSorter(t *testing.T) {
+	for _, chunkSize := range []int{1, 2, 4, 100} {
+		for caseIndex, testCase := range testRowSorterCases {
+			sorter, _ := NewRowSorter(testCase.spec)
+			sorter.NullsLast = testCase.nullsLast
+			external := NewExternalSorter(sorter, chunkSize)
+			external.TempDir = t.TempDir()
+			for _, row := range testSortRows() {
+				if err := external.Add(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rows := []KeyValue{}
+			err := external.Each(func(row KeyValue) error {
+				rows = append(rows, row)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := external.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if actual := sortedIDs(rows); !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("%d/%d: Expected %v, but %v", chunkSize, caseIndex, testCase.expected, actual)
+			}
+			for _, row := range rows {
+				if _, ok := row["/id"].(json.Number); !ok {
+					t.Errorf("%d/%d: Expected json.Number, but %T", chunkSize, caseIndex, row["/id"])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// SyntheticSpec generates deterministic JSON records for tests and
+// benchmarks. It is exported for the tests of package json2csv_test.
+//
+// Each object has Width fields named "f0", "f1", ..., whose types cycle
+// through object, array, number, string, number, boolean and null. Objects
+// are nested up to Depth levels, and arrays have ArrayLen elements, which are
+// objects at odd fields (below Depth) and primitives otherwise. Each field
+// is missing with the probability of Sparsity. Records of the same Seed and
+// index are always the same.
+type SyntheticSpec struct {
+	Seed     int64
+	Width    int
+	Depth    int
+	ArrayLen int
+	Sparsity float64
+}
+
+// syntheticBenchmarks are the specs of benchmarks.
+var syntheticBenchmarks = []struct {
+	Name string
+	Spec SyntheticSpec
+}{
+	{"narrow", SyntheticSpec{Width: 7, Depth: 2, ArrayLen: 2}},
+	{"wide", SyntheticSpec{Width: 200, Depth: 1, ArrayLen: 3}},
+	{"deep", SyntheticSpec{Width: 3, Depth: 8, ArrayLen: 1}},
+	{"sparse", SyntheticSpec{Width: 100, Depth: 2, ArrayLen: 2, Sparsity: 0.9}},
+}
+
+// Record returns the i-th record as decoded by encoding/json with UseNumber.
+func (s SyntheticSpec) Record(i int) map[string]interface{} {
+	r := rand.New(rand.NewSource(s.Seed*1000003 + int64(i)))
+	return s.object(r, s.Depth)
+}
+
+// Records returns n records as a JSON array.
+func (s SyntheticSpec) Records(n int) []interface{} {
+	records := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		records = append(records, s.Record(i))
+	}
+	return records
+}
+
+// WriteLines writes n records as JSON lines.
+func (s SyntheticSpec) WriteLines(w io.Writer, n int) error {
+	b := bufio.NewWriter(w)
+	e := json.NewEncoder(b)
+	for i := 0; i < n; i++ {
+		if err := e.Encode(s.Record(i)); err != nil {
+			return err
+		}
+	}
+	return b.Flush()
+}
+
+func (s SyntheticSpec) object(r *rand.Rand, depth int) map[string]interface{} {
+	obj := make(map[string]interface{}, s.Width)
+	for j := 0; j < s.Width; j++ {
+		if s.Sparsity > 0 && r.Float64() < s.Sparsity {
+			continue
+		}
+		obj["f"+strconv.Itoa(j)] = s.value(r, j, depth)
+	}
+	return obj
+}
+
+func (s SyntheticSpec) value(r *rand.Rand, field int, depth int) interface{} {
+	switch field % 7 {
+	case 0:
+		if depth > 1 {
+			return s.object(r, depth-1)
+		}
+	case 1:
+		if s.ArrayLen > 0 {
+			arr := make([]interface{}, 0, s.ArrayLen)
+			for k := 0; k < s.ArrayLen; k++ {
+				if field%2 == 1 && depth > 1 {
+					arr = append(arr, s.object(r, depth-1))
+				} else {
+					arr = append(arr, s.primitive(r, k))
+				}
+			}
+			return arr
+		}
+	case 5:
+		return r.Intn(2) == 0
+	case 6:
+		return nil
+	}
+	return s.primitive(r, field)
+}
+
+func (s SyntheticSpec) primitive(r *rand.Rand, n int) interface{} {
+	switch n % 3 {
+	case 0:
+		return json.Number(strconv.Itoa(r.Intn(1000000)))
+	case 1:
+		return fmt.Sprintf("value-%d", r.Intn(100000))
+	default:
+		return json.Number(strconv.FormatFloat(r.Float64()*1000, 'f', 3, 64))
+	}
+}
+
+func TestSyntheticSpec(t *testing.T) {
+	for _, bm := range syntheticBenchmarks {
+		var a, b bytes.Buffer
+		if err := bm.Spec.WriteLines(&a, 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := bm.Spec.WriteLines(&b, 10); err != nil {
+			t.Fatal(err)
+		}
+		if a.String() != b.String() {
+			t.Errorf("%s: Expected the same records", bm.Name)
+		}
+
+		// records are the same as decoded from the lines
+		for i, line := range strings.Split(strings.TrimSpace(a.String()), "\n") {
+			decoded, err := json2obj(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, bm.Spec.Record(i)) {
+				t.Errorf("%s: Expected %v, but %v", bm.Name, bm.Spec.Record(i), decoded)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package json2csv
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	tableColumnSeparator = "  "
+	tableEllipsis        = "…"
+)
+
+// TableWriter writes fixed-width text tables for terminals.
+type TableWriter struct {
+	w           io.Writer
+	HeaderStyle KeyStyle
+	Transpose   bool
+
+	// Width is the width of the terminal. If the table is wider than Width,
+	// it is written in the transposed layout. 0 means unlimited.
+	Width int
+
+	// MaxColumnWidth truncates cells wider than it with an ellipsis.
+	// 0 means unlimited.
+	MaxColumnWidth int
+
+	// ValueFormatter formats each value. If nil, values are formatted as is.
+	ValueFormatter ValueFormatter
+
+	// KeyFormatter formats header keys instead of HeaderStyle if not nil.
+	KeyFormatter KeyFormatter
+
+	// Renamer renames header keys after HeaderStyle is applied.
+	Renamer *Renamer
+}
+
+// NewTableWriter returns new TableWriter with given KeyStyle and transpose.
+func NewTableWriter(w io.Writer, style KeyStyle, transpose bool) *TableWriter {
+	return &TableWriter{
+		w:           w,
+		HeaderStyle: style,
+		Transpose:   transpose,
+	}
+}
+
+// WriteTable writes data as an aligned text table.
+func (w *TableWriter) WriteTable(results []KeyValue) error {
+	pts, err := allPointers(results)
+	if err != nil {
+		return err
+	}
+	sort.Sort(pts)
+	keys := pts.Strings()
+	header, err := formatHeader(pts, keyFormatterOf(w.HeaderStyle, w.KeyFormatter), w.Renamer)
+	if err != nil {
+		return err
+	}
+
+	if !w.Transpose {
+		records := make([][]string, 0, len(results)+1)
+		records = append(records, header)
+		for _, result := range results {
+			records = append(records, toRecord(result, keys, w.ValueFormatter, ""))
+		}
+		widths := w.columnWidths(records)
+		if w.Width <= 0 || tableWidth(widths) <= w.Width {
+			return w.writeRecords(records, widths, true)
+		}
+	}
+
+	records := make([][]string, 0, len(keys))
+	for i, key := range keys {
+		records = append(records, toTransposedRecord(results, key, header[i], w.ValueFormatter, ""))
+	}
+	return w.writeRecords(records, w.columnWidths(records), false)
+}
+
+// WriteRecords writes the header and records as an aligned text table.
+func (w *TableWriter) WriteRecords(header []string, records [][]string) error {
+	all := make([][]string, 0, len(records)+1)
+	all = append(all, header)
+	all = append(all, records...)
+	return w.writeRecords(all, w.columnWidths(all), true)
+}
+
+func (w *TableWriter) columnWidths(records [][]string) []int {
+	widths := []int{}
+	for _, record := range records {
+		for i, cell := range record {
+			cw := stringWidth(sanitizeCell(cell))
+			if w.MaxColumnWidth > 0 && cw > w.MaxColumnWidth {
+				cw = w.MaxColumnWidth
+			}
+			if i >= len(widths) {
+				widths = append(widths, cw)
+			} else if cw > widths[i] {
+				widths[i] = cw
+			}
+		}
+	}
+	return widths
+}
+
+func (w *TableWriter) writeRecords(records [][]string, widths []int, withRule bool) error {
+	for n, record := range records {
+		if err := w.writeLine(record, widths); err != nil {
+			return err
+		}
+		if n == 0 && withRule {
+			rule := make([]string, len(widths))
+			for i, cw := range widths {
+				rule[i] = strings.Repeat("-", cw)
+			}
+			if err := w.writeLine(rule, widths); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *TableWriter) writeLine(record []string, widths []int) error {
+	var b strings.Builder
+	for i, cell := range record {
+		cell = truncateString(sanitizeCell(cell), w.MaxColumnWidth)
+		if i > 0 {
+			b.WriteString(tableColumnSeparator)
+		}
+		b.WriteString(cell)
+		if i < len(record)-1 {
+			b.WriteString(strings.Repeat(" ", widths[i]-stringWidth(cell)))
+		}
+	}
+	_, err := io.WriteString(w.w, strings.TrimRight(b.String(), " ")+"\n")
+	return err
+}
+
+func tableWidth(widths []int) int {
+	total := 0
+	for _, cw := range widths {
+		total += cw
+	}
+	if len(widths) > 1 {
+		total += len(tableColumnSeparator) * (len(widths) - 1)
+	}
+	return total
+}
+
+// sanitizeCell replaces control characters which break the layout.
+func sanitizeCell(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// truncateString truncates s to the given display width with an ellipsis.
+func truncateString(s string, width int) string {
+	if width <= 0 || stringWidth(s) <= width {
+		return s
+	}
+
+	limit := width - stringWidth(tableEllipsis)
+	var b strings.Builder
+	cur := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if cur+rw > limit {
+			break
+		}
+		b.WriteRune(r)
+		cur += rw
+	}
+	b.WriteString(tableEllipsis)
+	return b.String()
+}
+
+// stringWidth returns the number of cells s occupies on a terminal.
+func stringWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// East Asian Wide (W) and Fullwidth (F) ranges.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xA960, 0xA97F},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth returns the number of cells r occupies on a terminal.
+func runeWidth(r rune) int {
+	if r == 0 || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, rg := range wideRanges {
+		if r < rg.lo {
+			break
+		}
+		if r <= rg.hi {
+			return 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,54 @@
+package json2csv_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/yukithm/json2csv"
+)
+
+var testTableData = []map[string]interface{}{
+	{"id": 1, "name": "foo", "note": "日本語のテキスト"},
+	{"id": 2, "name": "barbaz"},
+}
+
+func TestWriteTable(t *testing.T) {
+	testCases := []struct {
+		width          int
+		maxColumnWidth int
+		want           string
+	}{
+		{0, 0, `/id  /name   /note
+---  ------  ----------------
+1    foo     日本語のテキスト
+2    barbaz
+`},
+		{0, 7, `/id  /name   /note
+---  ------  -------
+1    foo     日本語…
+2    barbaz
+`},
+		{20, 0, `/id    1                 2
+/name  foo               barbaz
+/note  日本語のテキスト
+`},
+	}
+
+	results, err := json2csv.JSON2CSV(testTableData, nil, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for caseIndex, testCase := range testCases {
+		b := &bytes.Buffer{}
+		wr := json2csv.NewTableWriter(b, json2csv.JSONPointerStyle, false)
+		wr.Width = testCase.width
+		wr.MaxColumnWidth = testCase.maxColumnWidth
+		if err := wr.WriteTable(results); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != testCase.want {
+			t.Errorf("%d: Expected\n%v\nbut\n%v", caseIndex, testCase.want, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// JSON2CSVTokens converts a JSON object like JSON2CSVWithOptions converts it
+// decoded, but walks the tokens of json.Decoder instead of decoding it into a
+// map, so that the values are put into the row without an intermediate tree.
+// Only the values which need the whole subtree, like those written as JSON
+// strings by MaxDepth or arrays of JoinArray, are decoded.
+//
+// As JSONStreamReader reads records, data which is not a JSON object or is
+// invalid results in no rows. If an object has duplicate keys, the last value
+// is used as decoding does.
+//
+// Filter of opts needs decoded records, so use JSON2CSVWithOptions with it.
+func JSON2CSVTokens(data []byte, opts *FlattenOptions) ([]KeyValue, error) {
+	f, err := newFlattener(opts)
+	if err != nil {
+		return nil, err
+	}
+	return flattenTokens(data, f)
+}
+
+func flattenTokens(data []byte, f *flattener) ([]KeyValue, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	t := &tokenFlattener{f: f, d: d, out: KeyValue{}}
+
+	results := []KeyValue{}
+	tok, err := d.Token()
+	if delim, ok := tok.(json.Delim); err != nil || !ok || delim != '{' || !d.More() {
+		return results, nil
+	}
+	var key jsonpointer.JSONPointer
+	if f.needsKey() {
+		key = jsonpointer.JSONPointer{}
+	}
+	limit := -1
+	if f.maxDepth > 0 {
+		limit = f.maxDepth
+	}
+	if err := t.container('{', key, "", 0, limit); err != nil {
+		// invalid records are ignored as JSONStreamReader does
+		return results, nil
+	}
+
+	for _, column := range f.columns {
+		if err := column.Apply(t.out); err != nil {
+			return nil, err
+		}
+	}
+	results = append(results, t.out)
+	if f.melt != nil {
+		return f.melt.Apply(results)
+	}
+	return results, nil
+}
+
+// tokenFlattener flattens values read from the decoder into out.
+// key and path are the same as flattenDecoded.
+type tokenFlattener struct {
+	f   *flattener
+	d   *json.Decoder
+	out KeyValue
+
+	// keys of the objects being read at each depth, to find duplicates
+	seen []map[string]struct{}
+}
+
+// value flattens the next value.
+func (t *tokenFlattener) value(key jsonpointer.JSONPointer, path string, depth int, limit int) error {
+	tok, err := t.d.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); ok {
+		return t.container(delim, key, path, depth, limit)
+	}
+	if tok != nil {
+		// string, json.Number or bool, and nulls are ignored
+		t.out[path] = tok
+	}
+	return nil
+}
+
+// container flattens the object or array whose delimiter has been read.
+func (t *tokenFlattener) container(delim json.Delim, key jsonpointer.JSONPointer, path string, depth int, limit int) error {
+	l := t.f.depthLimit(key, limit)
+	if (l >= 0 && depth >= l) || (delim == '[' && t.f.policyOf(key) != IndexArray) {
+		// decode the subtree, and flatten it as decoded
+		v, err := t.rest(delim)
+		if err != nil {
+			return err
+		}
+		_, err = t.f.flattenDecoded(t.out, v, key, path, depth, limit)
+		return err
+	}
+
+	if delim == '{' {
+		seen := t.seenAt(depth)
+		for t.d.More() {
+			tok, err := t.d.Token()
+			if err != nil {
+				return err
+			}
+			name, _ := tok.(string)
+			child, childPath := t.child(key, path, name)
+			if _, ok := seen[name]; ok {
+				t.drop(childPath)
+			} else {
+				seen[name] = struct{}{}
+			}
+			if err := t.value(child, childPath, depth+1, l); err != nil {
+				return err
+			}
+		}
+	} else {
+		for i := 0; t.d.More(); i++ {
+			if i >= t.f.sliceLen {
+				if err := t.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			child, childPath := t.child(key, path, strconv.Itoa(i))
+			if err := t.value(child, childPath, depth+1, l); err != nil {
+				return err
+			}
+		}
+	}
+	// the closing delimiter
+	_, err := t.d.Token()
+	return err
+}
+
+// seenAt returns the empty set of keys of the object at the depth.
+func (t *tokenFlattener) seenAt(depth int) map[string]struct{} {
+	for len(t.seen) <= depth {
+		t.seen = append(t.seen, map[string]struct{}{})
+	}
+	clear(t.seen[depth])
+	return t.seen[depth]
+}
+
+// drop removes the values at the path and under it, which are replaced by
+// the value of a duplicate key.
+func (t *tokenFlattener) drop(path string) {
+	for key := range t.out {
+		if key == path || strings.HasPrefix(key, path+"/") {
+			delete(t.out, key)
+		}
+	}
+}
+
+// child returns the key and the path of the token under the key.
+func (t *tokenFlattener) child(key jsonpointer.JSONPointer, path string, token string) (jsonpointer.JSONPointer, string) {
+	var child jsonpointer.JSONPointer
+	if t.f.needsKey() {
+		child = append(key[:len(key):len(key)], jsonpointer.Token(token))
+	}
+	return child, path + "/" + escapeToken(token)
+}
+
+// rest decodes the rest of the object or array whose delimiter has been read.
+func (t *tokenFlattener) rest(delim json.Delim) (interface{}, error) {
+	if delim == '{' {
+		obj := map[string]interface{}{}
+		for t.d.More() {
+			tok, err := t.d.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, _ := tok.(string)
+			if obj[name], err = t.next(); err != nil {
+				return nil, err
+			}
+		}
+		_, err := t.d.Token()
+		return obj, err
+	}
+
+	arr := []interface{}{}
+	for t.d.More() {
+		v, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	_, err := t.d.Token()
+	return arr, err
+}
+
+// next decodes the next value.
+func (t *tokenFlattener) next() (interface{}, error) {
+	tok, err := t.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); ok {
+		return t.rest(delim)
+	}
+	return tok, nil
+}
+
+// skip skips the next value.
+func (t *tokenFlattener) skip() error {
+	depth := 0
+	for {
+		tok, err := t.d.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testJSON2CSVTokensCases = []struct {
+	json string
+	opts FlattenOptions
+}{
+	{`{"a": 1, "b": "x", "c": true, "d": null, "e": 1.5e300, "f": {}, "g": []}`, FlattenOptions{}},
+	{`{"a": {"b": [1, {"c": [null, "x"]}]}, "a/b": {"~": 2}, "": {"": 3}}`, FlattenOptions{}},
+	{`{"a": [1, 2, 3, 4], "b": [[1, 2, 3], [{"c": [4]}]], "c": [{"d": [5, 6]}, 7, 8]}`, FlattenOptions{SliceLen: 2}},
+	{`{"a": {"b": {"c": 1}}, "p": {"x": {"y": [2]}}, "q": [{"r": {}}]}`, FlattenOptions{MaxDepth: 1, MaxDepthOverrides: map[string]int{"/p": 1, "/q/*": 2}}},
+	{`{"a": {"b": {"c": {"d": 1}}}}`, FlattenOptions{MaxDepth: 3}},
+	{`{"t": ["a", null, 1, true], "n": [{"x": 1}], "e": []}`, FlattenOptions{ArrayPolicy: JoinArray, ArraySeparator: "|"}},
+	{`{"t": ["a", 1], "n": [{"x": 1}], "e": [], "o": {"p": [[1]]}}`, FlattenOptions{ArrayPolicy: JSONArray}},
+	{`{"t": ["a", 1], "n": [{"x": [1, 2]}], "e": []}`, FlattenOptions{ArrayPolicy: CountArray, ArrayPolicyOverrides: map[string]ArrayPolicy{"/n/*/x": IndexArray, "/n": IndexArray}}},
+	{`{"s": "a\"b\\cé\n", "n": -0.5e-3, "big": 123456789012345678901234567890}`, FlattenOptions{}},
+	{`{"a": null}`, FlattenOptions{}},
+	{`{}`, FlattenOptions{}},
+	{`[{"a": 1}]`, FlattenOptions{}},
+	{`"a"`, FlattenOptions{}},
+	{`1`, FlattenOptions{}},
+	{``, FlattenOptions{}},
+	{`{"a": 1,}`, FlattenOptions{}},
+	{`{"a": [1, {"b": }]}`, FlattenOptions{}},
+	{`{"a": 1`, FlattenOptions{}},
+	{`{"a": 1} {"b": 2}`, FlattenOptions{}},
+	{`{"a": {"x": 1}, "a": {"y": 2}}`, FlattenOptions{}},
+	{`{"a": 1, "a": null}`, FlattenOptions{}},
+	{`{"a": [1, 2, 3], "b": 1, "a": [4], "ab": 2}`, FlattenOptions{}},
+	{`{"a": {"b": {"c": 1}, "b": {"d": 2}}, "a": {"e": 3, "e": 4}}`, FlattenOptions{MaxDepth: 2}},
+	{`{"a": {"b": 1}, "a": "x", "a/b": 2}`, FlattenOptions{ArrayPolicy: JoinArray}},
+}
+
+func TestJSON2CSVTokens(t *testing.T) {
+	for caseIndex, testCase := range testJSON2CSVTokensCases {
+		opts := testCase.opts
+		if opts.SliceLen == 0 {
+			opts.SliceLen = NewFlattenOptions().SliceLen
+		}
+		expected, expectedErr := decodeAndFlatten([]byte(testCase.json), &opts)
+		actual, err := JSON2CSVTokens([]byte(testCase.json), &opts)
+		if (err == nil) != (expectedErr == nil) {
+			t.Errorf("%d: Expected error %v, but %v", caseIndex, expectedErr, err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, expected, actual)
+		}
+	}
+}
+
+func TestJSON2CSVTokensColumnsAndMelt(t *testing.T) {
+	opts := NewFlattenOptions()
+	column, err := ParseComputedColumn("total=/metrics/cpu + /metrics/mem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Columns = []*ComputedColumn{column}
+	if opts.Melt, err = NewMelt("/metrics"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`{"id": 1, "metrics": {"cpu": 1, "mem": 2}}`)
+	expected, err := decodeAndFlatten(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := JSON2CSVTokens(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestJSON2CSVTokensSynthetic(t *testing.T) {
+	for _, bm := range syntheticBenchmarks {
+		var b bytes.Buffer
+		if err := bm.Spec.WriteLines(&b, 20); err != nil {
+			t.Fatal(err)
+		}
+		for i, line := range bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n")) {
+			for _, opts := range []*FlattenOptions{
+				NewFlattenOptions(),
+				{SliceLen: 1, MaxDepth: 2, ArrayPolicy: JoinArray, ArraySeparator: ";"},
+			} {
+				expected, _ := decodeAndFlatten(line, opts)
+				actual, err := JSON2CSVTokens(line, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("%s %d: Expected %v, but %v", bm.Name, i, expected, actual)
+				}
+			}
+		}
+	}
+}
+
+// decodeAndFlatten flattens data as JSONStreamReader.Read decodes it.
+func decodeAndFlatten(data []byte, opts *FlattenOptions) ([]KeyValue, error) {
+	obj := map[string]interface{}{}
+	_ = decodeJSONObject(data, &obj)
+	return JSON2CSVWithOptions(obj, nil, opts)
+}
+
+func BenchmarkJSON2CSVTokens(b *testing.B) {
+	for _, bm := range syntheticBenchmarks {
+		lines := make([][]byte, 0, 100)
+		for _, record := range bm.Spec.Records(100) {
+			line, err := json.Marshal(record)
+			if err != nil {
+				b.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		opts := NewFlattenOptions()
+		b.Run(bm.Name+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, line := range lines {
+					if _, err := decodeAndFlatten(line, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run(bm.Name+"/tokens", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, line := range lines {
+					if _, err := JSON2CSVTokens(line, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+)
+
+// DefaultTransposeBufferSize is the default BufferSize of Transposer.
+const DefaultTransposeBufferSize = 64 << 20
+
+// Transposer writes rows of a stream transposed, as WriteCSV of a CSVWriter
+// with Transpose writes them in memory.
+//
+// The first line of the output needs the last row, so cells are buffered by
+// column, and the buffers are spilled to a temporary file when they exceed
+// BufferSize bytes. Finish writes the lines, and Close removes the file.
+type Transposer struct {
+	// BufferSize is the bytes of cells held in memory.
+	BufferSize int
+
+	// TempDir is the directory of the temporary file.
+	// If empty, the default directory for temporary files is used.
+	TempDir string
+
+	writer   *CSVWriter
+	header   *Header
+	columns  [][]byte    // encoded cells of each column, each after a comma
+	spilled  [][]segment // segments of each column in the file
+	buffered int
+	file     *os.File
+	size     int64
+
+	field    bytes.Buffer
+	encoder  *csv.Writer
+	trailing int
+}
+
+// segment is a part of a column in the temporary file.
+type segment struct {
+	offset int64
+	length int64
+}
+
+// NewTransposer returns new Transposer which writes rows of the header with
+// the writer. The writer must be created by NewCSVWriter, and its Comma and
+// UseCRLF must not be changed after this.
+func (w *CSVWriter) NewTransposer(header *Header) *Transposer {
+	t := &Transposer{
+		BufferSize: DefaultTransposeBufferSize,
+		writer:     w,
+		header:     header,
+		columns:    make([][]byte, header.Len()),
+		spilled:    make([][]segment, header.Len()),
+	}
+	// fields are encoded by csv.Writer with an empty field after them,
+	// which is trimmed with the line terminator.
+	t.encoder = csv.NewWriter(&t.field)
+	t.encoder.Comma = w.Comma
+	t.encoder.UseCRLF = w.UseCRLF
+	t.trailing = len(string(w.Comma)) + len(t.lineTerminator())
+	return t
+}
+
+// Write adds the row.
+func (t *Transposer) Write(row KeyValue) error {
+	return t.writeRecord(t.writer.record(row, t.header))
+}
+
+// writeRecord adds the record of the row in the order of the header.
+func (t *Transposer) writeRecord(record []string) error {
+	comma := string(t.writer.Comma)
+	for i, value := range record {
+		field, err := t.encode(value)
+		if err != nil {
+			return err
+		}
+		t.columns[i] = append(t.columns[i], comma...)
+		t.columns[i] = append(t.columns[i], field...)
+		t.buffered += len(comma) + len(field)
+	}
+	if t.buffered > t.BufferSize {
+		return t.spill()
+	}
+	return nil
+}
+
+// encode returns the field quoted as csv.Writer does.
+func (t *Transposer) encode(value string) ([]byte, error) {
+	t.field.Reset()
+	if err := t.encoder.Write([]string{value, ""}); err != nil {
+		return nil, err
+	}
+	t.encoder.Flush()
+	if err := t.encoder.Error(); err != nil {
+		return nil, err
+	}
+	b := t.field.Bytes()
+	return b[:len(b)-t.trailing], nil
+}
+
+// spill appends the buffered cells to the temporary file.
+func (t *Transposer) spill() error {
+	if t.file == nil {
+		f, err := os.CreateTemp(t.TempDir, "json2csv-transpose-*")
+		if err != nil {
+			return err
+		}
+		t.file = f
+	}
+	w := bufio.NewWriter(t.file)
+	for i, column := range t.columns {
+		if len(column) == 0 {
+			continue
+		}
+		if _, err := w.Write(column); err != nil {
+			return err
+		}
+		t.spilled[i] = append(t.spilled[i], segment{t.size, int64(len(column))})
+		t.size += int64(len(column))
+		t.columns[i] = column[:0]
+	}
+	t.buffered = 0
+	return w.Flush()
+}
+
+// Finish writes a line for each column of the header.
+func (t *Transposer) Finish() error {
+	if t.writer.out == nil {
+		return errors.New("Transposer needs CSVWriter created by NewCSVWriter")
+	}
+	t.writer.Flush()
+	if err := t.writer.Error(); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(t.writer.out)
+	for i, name := range t.header.Names {
+		field, err := t.encode(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(field); err != nil {
+			return err
+		}
+		for _, s := range t.spilled[i] {
+			if _, err := io.Copy(w, io.NewSectionReader(t.file, s.offset, s.length)); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(t.columns[i]); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(t.lineTerminator()); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (t *Transposer) lineTerminator() string {
+	if t.writer.UseCRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// Close removes the temporary file.
+func (t *Transposer) Close() error {
+	t.columns = nil
+	if t.file == nil {
+		return nil
+	}
+	err := errors.Join(t.file.Close(), os.Remove(t.file.Name()))
+	t.file = nil
+	return err
+}
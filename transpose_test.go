@@ -0,0 +1,112 @@
+package json2csv
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+)
+
+var testTransposerCases = []struct {
+	rows      []KeyValue
+	comma     rune
+	useCRLF   bool
+	nullValue string
+}{
+	{[]KeyValue{{"/a": 1, "/b": "x"}, {"/a": 2}, {"/b": true}}, ',', false, ""},
+	{[]KeyValue{{"/a": "x,y", "/b": "q\"uote"}, {"/a": " space", "/b": "line\nbreak"}, {"/a": `\.`, "/b": ""}}, ',', false, ""},
+	{[]KeyValue{{"/a": "x;y", "/b": "line\nbreak"}, {"/a": "x,y"}}, ';', true, "NULL"},
+	{[]KeyValue{{"/a": "ü", "/c d": 1.5}, {"/c d": "é\té"}}, '\t', false, "-"},
+	{[]KeyValue{}, ',', false, ""},
+}
+
+func TestTransposer(t *testing.T) {
+	for caseIndex, testCase := range testTransposerCases {
+		newWriter := func(b *bytes.Buffer) *CSVWriter {
+			w := NewCSVWriter(b, DotBracketStyle, true)
+			w.Comma = testCase.comma
+			w.UseCRLF = testCase.useCRLF
+			w.NullValue = testCase.nullValue
+			return w
+		}
+		var expected bytes.Buffer
+		if err := newWriter(&expected).WriteCSV(testCase.rows); err != nil {
+			t.Fatal(err)
+		}
+
+		csvHeader := CSVHeader{}
+		for _, row := range testCase.rows {
+			for key := range row {
+				csvHeader[key] = ""
+			}
+		}
+		for _, bufferSize := range []int{0, 10, DefaultTransposeBufferSize} {
+			var b bytes.Buffer
+			w := newWriter(&b)
+			header, err := w.CompileHeader(csvHeader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transposer := w.NewTransposer(header)
+			transposer.BufferSize = bufferSize
+			transposer.TempDir = t.TempDir()
+			for _, row := range testCase.rows {
+				if err := transposer.Write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := transposer.Finish(); err != nil {
+				t.Fatal(err)
+			}
+			if err := transposer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != expected.String() {
+				t.Errorf("%d: BufferSize %d: Expected %q, but %q", caseIndex, bufferSize, expected.String(), b.String())
+			}
+		}
+	}
+}
+
+func TestConverterStreamTranspose(t *testing.T) {
+	filename := createTestLines(t, 200)
+	opts := &FlattenOptions{SliceLen: math.MaxInt}
+
+	// the in-memory output of all rows
+	var rows []KeyValue
+	reader := openTestLines(t, filename)
+	if err := newTestConverter(t, Options{Flatten: opts}).EachRow(context.Background(), reader, func(row KeyValue) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	var expected bytes.Buffer
+	if err := NewCSVWriter(&expected, DotBracketStyle, true).WriteCSV(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		c, err := NewConverter(Options{Flatten: opts, KeyStyle: DotBracketStyle, Transpose: true, Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := openTestLines(t, filename)
+		header, err := c.Header(context.Background(), reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		reader = openTestLines(t, filename)
+		err = c.ConvertStream(context.Background(), reader, header, &b)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected.String() {
+			t.Errorf("workers=%d: Expected %q, but %q", workers, expected.String(), b.String())
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package json2csv
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 func valueOf(obj interface{}) reflect.Value {
@@ -18,5 +22,36 @@ func valueOf(obj interface{}) reflect.Value {
 }
 
 func toString(obj interface{}) string {
+	// the same as %v for the common types, without fmt
+	switch v := obj.(type) {
+	case string:
+		return v
+	case json.Number:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
 	return fmt.Sprintf("%v", obj)
 }
+
+// decodeJSONObject decodes JSON with UseNumber option.
+func decodeJSONObject(data []byte, obj *map[string]interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(obj)
+}
+
+// compactJSON returns the compact JSON representation of obj.
+func compactJSON(obj interface{}) (string, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(obj); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
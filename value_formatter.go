@@ -0,0 +1,124 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ValueFormatter formats a flattened value into a CSV field.
+type ValueFormatter interface {
+	FormatValue(value interface{}) string
+}
+
+// ValueFormatterFunc is an adapter to use ordinary functions as ValueFormatter.
+type ValueFormatterFunc func(value interface{}) string
+
+// FormatValue calls f(value).
+func (f ValueFormatterFunc) FormatValue(value interface{}) string {
+	return f(value)
+}
+
+// BoolStyle represents the representation of boolean values.
+type BoolStyle uint
+
+// Boolean styles
+const (
+	// "true", "false"
+	TrueFalseBool BoolStyle = iota
+
+	// "TRUE", "FALSE"
+	UpperTrueFalseBool
+
+	// "1", "0"
+	OneZeroBool
+
+	// "yes", "no"
+	YesNoBool
+)
+
+var boolStyleTable = map[BoolStyle][2]string{
+	TrueFalseBool:      {"true", "false"},
+	UpperTrueFalseBool: {"TRUE", "FALSE"},
+	OneZeroBool:        {"1", "0"},
+	YesNoBool:          {"yes", "no"},
+}
+
+// NumberFormat is a ValueFormatter which formats numbers and booleans.
+// Other values are formatted as is, and so is everything by the zero value.
+type NumberFormat struct {
+	// Fixed formats floating point numbers with Decimals digits after the
+	// decimal point. Integers, which are numbers without a decimal point or
+	// an exponent like 2, are unchanged.
+	Fixed    bool
+	Decimals int
+
+	// NoExponent prevents scientific notation like "1e+06".
+	NoExponent bool
+
+	// BoolStyle is the representation of boolean values.
+	BoolStyle BoolStyle
+
+	// DecimalSeparator replaces the decimal point. Empty means ".".
+	DecimalSeparator string
+}
+
+// NewNumberFormat returns new NumberFormat which formats values as is.
+func NewNumberFormat() *NumberFormat {
+	return &NumberFormat{}
+}
+
+// FormatValue formats the value.
+func (f *NumberFormat) FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return boolStyleTable[f.BoolStyle][0]
+		}
+		return boolStyleTable[f.BoolStyle][1]
+	case json.Number:
+		s := string(v)
+		if !strings.ContainsAny(s, ".eE") {
+			return s
+		}
+		if !f.Fixed && (!f.NoExponent || !strings.ContainsAny(s, "eE")) {
+			return f.separate(s)
+		}
+		n, err := v.Float64()
+		if err != nil {
+			return s
+		}
+		return f.formatFloat(n)
+	case float64:
+		return f.formatFloat(v)
+	default:
+		return toString(value)
+	}
+}
+
+func (f *NumberFormat) formatFloat(n float64) string {
+	var s string
+	switch {
+	case f.Fixed:
+		s = strconv.FormatFloat(n, 'f', f.Decimals, 64)
+	case f.NoExponent:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		s = toString(n)
+	}
+	return f.separate(s)
+}
+
+func (f *NumberFormat) separate(s string) string {
+	if f.DecimalSeparator == "" || f.DecimalSeparator == "." {
+		return s
+	}
+	return strings.Replace(s, ".", f.DecimalSeparator, 1)
+}
+
+func formatValue(formatter ValueFormatter, value interface{}) string {
+	if formatter == nil {
+		return toString(value)
+	}
+	return formatter.FormatValue(value)
+}
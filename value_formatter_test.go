@@ -0,0 +1,69 @@
+package json2csv_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/yukithm/json2csv"
+)
+
+var testNumberFormatCases = []struct {
+	format   json2csv.NumberFormat
+	value    interface{}
+	expected string
+}{
+	{json2csv.NumberFormat{}, float64(1000000), "1e+06"},
+	{json2csv.NumberFormat{NoExponent: true}, float64(1000000), "1000000"},
+	{json2csv.NumberFormat{NoExponent: true}, json.Number("1.5e3"), "1500"},
+	{json2csv.NumberFormat{NoExponent: true}, json.Number("1.5"), "1.5"},
+	{json2csv.NumberFormat{NoExponent: true}, float64(1.5), "1.5"},
+	{json2csv.NumberFormat{Fixed: true}, json.Number("1.5"), "2"},
+	{json2csv.NumberFormat{}, json.Number("1.50"), "1.50"},
+	{json2csv.NumberFormat{Fixed: true, Decimals: 2}, json.Number("1.5"), "1.50"},
+	{json2csv.NumberFormat{Fixed: true, Decimals: 2}, json.Number("15"), "15"},
+	{json2csv.NumberFormat{Fixed: true, Decimals: 1}, float64(0.25), "0.2"},
+	{json2csv.NumberFormat{DecimalSeparator: ","}, json.Number("3.14"), "3,14"},
+	{json2csv.NumberFormat{Fixed: true, Decimals: 3, DecimalSeparator: ","}, float64(2), "2,000"},
+	{json2csv.NumberFormat{BoolStyle: json2csv.TrueFalseBool}, true, "true"},
+	{json2csv.NumberFormat{BoolStyle: json2csv.UpperTrueFalseBool}, false, "FALSE"},
+	{json2csv.NumberFormat{BoolStyle: json2csv.OneZeroBool}, true, "1"},
+	{json2csv.NumberFormat{BoolStyle: json2csv.YesNoBool}, false, "no"},
+	{json2csv.NumberFormat{Fixed: true, Decimals: 2, DecimalSeparator: ","}, "1.5", "1.5"},
+}
+
+func TestNumberFormat(t *testing.T) {
+	for caseIndex, testCase := range testNumberFormatCases {
+		actual := testCase.format.FormatValue(testCase.value)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestCSVWriterValueFormatter(t *testing.T) {
+	b := &bytes.Buffer{}
+	wr := json2csv.NewCSVWriter(b, json2csv.JSONPointerStyle, false)
+	wr.ValueFormatter = json2csv.ValueFormatterFunc(func(value interface{}) string {
+		if value == true {
+			return "Y"
+		}
+		return "N"
+	})
+	results, err := json2csv.JSON2CSV([]map[string]interface{}{
+		{"a": true},
+		{"a": false},
+	}, nil, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteCSV(results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/a\nY\nN\n"
+	if got := b.String(); got != want {
+		t.Errorf("Expected %q, but %q", want, got)
+	}
+}